@@ -0,0 +1,78 @@
+package lightwork
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// sanitiseFilename strips any directory components and control characters from the filename, so that it's safe to use in a header.
+func sanitiseFilename(filename string) (sanitised string) {
+	filename = path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if filename == "." || filename == "/" {
+		return "download"
+	}
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, filename)
+}
+
+// encodeRFC5987 percent-encodes every byte of the value that isn't an attr-char, as defined by RFC 5987.
+func encodeRFC5987(value string) (encoded string) {
+	const attrChars = "!#$&+-.^_`|~"
+	sb := strings.Builder{}
+	for _, b := range []byte(value) {
+		isAlphaNum := (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+		if isAlphaNum || strings.IndexByte(attrChars, b) >= 0 {
+			sb.WriteByte(b)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
+
+// contentDisposition returns the value of a Content-Disposition header for downloading a file with the provided name.
+// Non-ASCII names are included using the RFC 5987 filename* parameter, with an ASCII fallback in the filename parameter.
+func contentDisposition(filename string) (value string) {
+	filename = sanitiseFilename(filename)
+	ascii := strings.Map(func(r rune) rune {
+		if r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, filename)
+	value = `attachment; filename="` + ascii + `"`
+	if ascii != filename {
+		value += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return
+}
+
+// Attachment returns the provided status code, then streams the file at diskPath as the body, with a Content-Disposition header that causes browsers to download it as the provided filename.
+// Any directory components in the filename are stripped. The file is streamed using StreamReadSeeker, so the Content-Length is set and Range requests are supported.
+func (cr ContextResponse) Attachment(statusCode int, filename, diskPath string) (err error) {
+	file, err := os.Open(diskPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	cr.Header().Set("Content-Disposition", contentDisposition(filename))
+	return cr.StreamReadSeeker(statusCode, file)
+}
+
+// Download returns the provided status code, then streams the provided Reader as the body, with a Content-Disposition header that causes browsers to download it as the provided filename.
+// Any directory components in the filename are stripped.
+func (cr ContextResponse) Download(statusCode int, filename string, stream io.Reader) (err error) {
+	cr.Header().Set("Content-Disposition", contentDisposition(filename))
+	if rs, ok := stream.(io.ReadSeeker); ok {
+		return cr.StreamReadSeeker(statusCode, rs)
+	}
+	return cr.Stream(statusCode, stream)
+}
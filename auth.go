@@ -0,0 +1,94 @@
+package lightwork
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// BasicAuthUserKey is the key under which the BasicAuth middleware stores the authenticated username on the Context.
+var BasicAuthUserKey = ContextKey[string]("basic auth user")
+
+// BasicAuth returns middleware that requires HTTP Basic authentication, using the provided function to validate credentials.
+// If the credentials are missing or invalid, a 401 is returned with a WWW-Authenticate header for the provided realm, without calling the next handler.
+// Otherwise, the username is stored on the Context under BasicAuthUserKey, and is available via ContextRequest.BasicAuthUser.
+func BasicAuth(realm string, validate func(c *Context, user, pass string) bool) Middleware {
+	challenge := `Basic realm="` + strings.ReplaceAll(realm, `"`, `\"`) + `"`
+	return func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			user, pass, ok := c.Request.req.BasicAuth()
+			if !ok || !validate(c, user, pass) {
+				c.Response.Header().Set("WWW-Authenticate", challenge)
+				return c.Response.Status(http.StatusUnauthorized)
+			}
+			BasicAuthUserKey.Set(c, user)
+			return next(c)
+		}
+	}
+}
+
+// BasicAuthStatic returns BasicAuth middleware that accepts only the provided username and password.
+// The credentials are compared in constant time.
+func BasicAuthStatic(realm, user, pass string) Middleware {
+	return BasicAuth(realm, func(_ *Context, u, p string) bool {
+		userMatch := subtle.ConstantTimeCompare([]byte(u), []byte(user))
+		passMatch := subtle.ConstantTimeCompare([]byte(p), []byte(pass))
+		return userMatch&passMatch == 1
+	})
+}
+
+// BasicAuthUser returns the username authenticated by the BasicAuth middleware, or an empty string if there isn't one.
+func (cr ContextRequest) BasicAuthUser() (user string) {
+	user, _ = BasicAuthUserKey.Get(cr.c)
+	return
+}
+
+// BearerClaimsKey is the key under which the BearerAuth middleware stores the verified token's claims on the Context.
+// The claims have whatever type was returned by the verify function, so ClaimsFrom is usually more convenient.
+var BearerClaimsKey = ContextKey[interface{}]("bearer claims")
+
+// bearerToken returns the token from the request's Authorization header, if it uses the Bearer scheme.
+func bearerToken(authorization string) (token string, ok bool) {
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return "", false
+	}
+	token = strings.TrimSpace(authorization[len(prefix):])
+	return token, token != ""
+}
+
+// BearerAuth returns middleware that requires a bearer token, such as a JWT, in the Authorization header, using the provided function to verify it.
+// The verify function is responsible for parsing and validating the token, and returns its claims if it's valid.
+// If the token is missing, or verify returns an error, a 401 is returned with a WWW-Authenticate header, without calling the next handler.
+// Otherwise, the claims are stored on the Context under BearerClaimsKey, and are available via ClaimsFrom.
+// BearerAuth and BasicAuth can be applied to different HandlerGroups, so that each group uses its own scheme.
+func BearerAuth(verify func(c *Context, token string) (claims interface{}, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			token, ok := bearerToken(c.Request.Header().Get("Authorization"))
+			if !ok {
+				c.Response.Header().Set("WWW-Authenticate", "Bearer")
+				return c.Response.Status(http.StatusUnauthorized)
+			}
+			claims, err := verify(c, token)
+			if err != nil {
+				c.Log.Infof("Bearer token verification failed: %v", err)
+				c.Response.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				return c.Response.Status(http.StatusUnauthorized)
+			}
+			BearerClaimsKey.Set(c, claims)
+			return next(c)
+		}
+	}
+}
+
+// ClaimsFrom returns the claims verified by the BearerAuth middleware, as the type returned by the verify function.
+// If there aren't any claims, or they have a different type, the zero value of T and false are returned.
+func ClaimsFrom[T any](c *Context) (claims T, ok bool) {
+	value, ok := BearerClaimsKey.Get(c)
+	if !ok {
+		return
+	}
+	claims, ok = value.(T)
+	return
+}
@@ -0,0 +1,35 @@
+package lightwork
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBearerAuthClaimsFrom(t *testing.T) {
+	type claims struct {
+		Subject string
+	}
+	s := newTestServer()
+	var got claims
+	var ok, wrongTypeOK bool
+	hg := s.GetHandlerGroup("")
+	hg.AddMiddleware(BearerAuth(func(c *Context, token string) (interface{}, error) {
+		return claims{Subject: token}, nil
+	}))
+	hg.GET("/", func(c *Context) (err error) {
+		got, ok = ClaimsFrom[claims](c)
+		_, wrongTypeOK = ClaimsFrom[string](c)
+		return c.Response.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer user-1")
+	serveTest(s, req)
+	if !ok || got.Subject != "user-1" {
+		t.Fatalf("expected the verified claims, got %+v (ok: %v)", got, ok)
+	}
+	if wrongTypeOK {
+		t.Fatal("expected claims of a different type not to be returned")
+	}
+}
@@ -0,0 +1,188 @@
+package lightwork
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// bindValues populates the fields of the struct pointed to by target that have the provided tag, using the provided lookup function.
+// Fields are only set if lookup returns true. Fields without the tag, or with the tag set to "-", are left as-is.
+func bindValues(target interface{}, tag string, lookup func(name string) (values []string, ok bool)) (err error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("bind target must be a non-nil pointer to a struct")
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, ok := field.Tag.Lookup(tag)
+		if !ok || name == "-" || field.PkgPath != "" {
+			continue
+		}
+		values, ok := lookup(name)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		err = setField(v.Field(i), values)
+		if err != nil {
+			return fmt.Errorf("failed to bind %s %q to field %s: %w", tag, name, field.Name, err)
+		}
+	}
+	return nil
+}
+
+// setField sets the field from the provided values.
+// Slice fields are set from all values, and other fields are set from the first value.
+func setField(field reflect.Value, values []string) (err error) {
+	if field.Kind() != reflect.Slice {
+		return setFieldValue(field, values[0])
+	}
+	slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+	for i, value := range values {
+		err = setFieldValue(slice.Index(i), value)
+		if err != nil {
+			return
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// setFieldValue parses the value according to the kind of the field, and sets the field to the result.
+func setFieldValue(field reflect.Value, value string) (err error) {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// checkContentType returns an error matching ErrUnsupportedMediaType if the media type of the request's Content-Type isn't the expected one, ignoring any parameters.
+func (cr ContextRequest) checkContentType(expected string) (err error) {
+	contentType := cr.Header().Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.EqualFold(mediaType, expected) {
+		return unsupportedMediaTypeError{contentType: contentType, expected: expected}
+	}
+	return nil
+}
+
+// hasBody returns whether the request may have a body.
+func (cr ContextRequest) hasBody() (hasBody bool) {
+	return cr.req.Body != nil && cr.req.Body != http.NoBody && cr.req.ContentLength != 0
+}
+
+// Bind populates the struct pointed to by target from the request body, path parameters, and query string.
+// The body is deserialised first using the server's DecodeStruct function, if the request has a body and DecodeStruct is configured.
+// As with BodyStructValidated, if the server's ExpectedRequestContentType is set, an error matching ErrUnsupportedMediaType is returned if the request has a body with a Content-Type that doesn't match it.
+// Then, fields tagged with `param:"name"` are set from path parameters, and fields tagged with `query:"name"` are set from the query string, overriding any values from the body.
+// Supported field types for parameters and query values are strings, bools, ints, uints, and floats, as well as slices of these for query values.
+// Finally, the struct is validated using the server's ValidateStruct function, if configured, as per BodyStructValidated.
+func (cr ContextRequest) Bind(target interface{}) (err error) {
+	if cr.hasBody() && cr.c.server.DecodeStruct != nil {
+		if cr.c.server.ExpectedRequestContentType != "" {
+			err = cr.checkContentType(cr.c.server.ExpectedRequestContentType)
+			if err != nil {
+				return
+			}
+		}
+		err = cr.c.server.DecodeStruct(cr.c, cr.cachedBodyStream(), target)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to decode body: %w", err)
+		}
+	}
+
+	err = bindValues(target, "param", func(name string) ([]string, bool) {
+		for _, p := range cr.params {
+			if p.Key == name {
+				return []string{p.Value}, true
+			}
+		}
+		return nil, false
+	})
+	if err != nil {
+		return
+	}
+	err = bindValues(target, "query", func(name string) ([]string, bool) {
+		values, ok := cr.queryValues()[name]
+		return values, ok
+	})
+	if err != nil {
+		return
+	}
+
+	if cr.c.server.ValidateStruct == nil {
+		return nil
+	}
+	err = cr.c.server.ValidateStruct(cr.c, target)
+	if err != nil {
+		return validationError{err: err}
+	}
+	return nil
+}
+
+// BodyForm parses the application/x-www-form-urlencoded body of the request, and populates the struct pointed to by result from fields tagged with `form:"name"`.
+// The supported field types are the same as for Bind, with slice fields set from repeated keys.
+// It returns an error matching ErrUnsupportedMediaType if the request's Content-Type isn't application/x-www-form-urlencoded. As with BodyBytes, the body is cached, so it can be read again afterwards.
+// If the form is parsed successfully, the struct is validated using the server's ValidateStruct function, if configured, as per BodyStructValidated.
+func (cr ContextRequest) BodyForm(result interface{}) (err error) {
+	err = cr.checkContentType("application/x-www-form-urlencoded")
+	if err != nil {
+		return
+	}
+
+	cr.cachedBodyStream()
+	err = cr.req.ParseForm()
+	if err != nil {
+		return fmt.Errorf("failed to parse form body: %w", err)
+	}
+	err = bindValues(result, "form", func(name string) ([]string, bool) {
+		values, ok := cr.req.PostForm[name]
+		return values, ok
+	})
+	if err != nil {
+		return
+	}
+
+	if cr.c.server.ValidateStruct == nil {
+		return nil
+	}
+	err = cr.c.server.ValidateStruct(cr.c, result)
+	if err != nil {
+		return validationError{err: err}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package lightwork
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBindChecksContentType(t *testing.T) {
+	s := newTestServer()
+	s.ExpectedRequestContentType = "application/json"
+	var bindErr error
+	s.GetHandlerGroup("").POST("/", func(c *Context) (err error) {
+		var target struct {
+			Name string `json:"name"`
+		}
+		bindErr = c.Request.Bind(&target)
+		return c.Response.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"lightwork"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	serveTest(s, req)
+	if !errors.Is(bindErr, ErrUnsupportedMediaType) {
+		t.Fatalf("expected ErrUnsupportedMediaType, got %v", bindErr)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"lightwork"}`))
+	req.Header.Set("Content-Type", "application/json")
+	serveTest(s, req)
+	if bindErr != nil {
+		t.Fatalf("unexpected error: %v", bindErr)
+	}
+}
@@ -0,0 +1,154 @@
+package lightwork
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+)
+
+// errNotBuffered is returned by ContextResponse.Reset if the response isn't being buffered.
+var errNotBuffered = errors.New("response isn't buffered, or has already been flushed")
+
+// bufferedResponseWriter captures the status code, headers, and body of the response in memory, until it's written out to the underlying writer.
+// Once written out, it passes everything through to the underlying writer.
+type bufferedResponseWriter struct {
+	rw         http.ResponseWriter
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+	flushed    bool
+}
+
+func newBufferedResponseWriter(rw http.ResponseWriter) (brw *bufferedResponseWriter) {
+	return &bufferedResponseWriter{
+		rw:     rw,
+		header: rw.Header().Clone(),
+	}
+}
+
+func (brw *bufferedResponseWriter) Header() (h http.Header) {
+	if brw.flushed {
+		return brw.rw.Header()
+	}
+	return brw.header
+}
+
+func (brw *bufferedResponseWriter) WriteHeader(statusCode int) {
+	if brw.flushed {
+		brw.rw.WriteHeader(statusCode)
+		return
+	}
+	brw.statusCode = statusCode
+}
+
+func (brw *bufferedResponseWriter) Write(b []byte) (n int, err error) {
+	if brw.flushed {
+		return brw.rw.Write(b)
+	}
+	return brw.body.Write(b)
+}
+
+// Flush writes out the buffered response, then flushes the underlying writer if possible.
+func (brw *bufferedResponseWriter) Flush() {
+	// Write errors mean the client has gone away, which the handler will find out about on its next write.
+	_ = brw.writeOut()
+	if f, ok := brw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeOut writes the buffered headers, status code, and body to the underlying writer, after which everything is passed through.
+func (brw *bufferedResponseWriter) writeOut() (err error) {
+	if brw.flushed {
+		return nil
+	}
+	brw.flushed = true
+	header := brw.rw.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range brw.header {
+		header[key] = values
+	}
+	if brw.statusCode != 0 {
+		brw.rw.WriteHeader(brw.statusCode)
+	}
+	if brw.body.Len() != 0 {
+		_, err = brw.rw.Write(brw.body.Bytes())
+		brw.body.Reset()
+	}
+	return
+}
+
+// Buffer starts buffering the response in memory, rather than writing it to the client immediately.
+// While buffered, the status code and headers can be changed even after the body has been written, and the response can be discarded using Reset.
+// The buffered response is written out when Flush is called, or when the handler returns. If the handler returns an error, the buffered response is discarded instead, so that the error is handled as if nothing had been written.
+// Buffering isn't suitable for streamed responses, and prevents the connection from being hijacked until the response is flushed.
+// Calling Buffer while the response is already buffered has no effect.
+func (cr ContextResponse) Buffer() {
+	if cr.c.buffer != nil {
+		return
+	}
+	cr.c.buffer = newBufferedResponseWriter(cr.rw.rw)
+	cr.rw.rw = cr.c.buffer
+}
+
+// Reset discards the buffered status code, headers, and body, restoring the headers to how they were when Buffer was called.
+// It returns an error if the response isn't buffered, or has already been flushed.
+func (cr ContextResponse) Reset() (err error) {
+	brw := cr.c.buffer
+	if brw == nil || brw.flushed {
+		return errNotBuffered
+	}
+	brw.header = brw.rw.Header().Clone()
+	brw.statusCode = 0
+	brw.body.Reset()
+	cr.rw.statusCode = 0
+	cr.rw.contentLength = 0
+	return nil
+}
+
+// endBuffer stops buffering the response, writing out the buffered response unless discard is set.
+func (cr ContextResponse) endBuffer(discard bool) {
+	brw := cr.c.buffer
+	if discard {
+		_ = cr.Reset()
+	} else if err := brw.writeOut(); err != nil {
+		cr.c.Log.Warningf("Failed to write buffered response: %v", err)
+	}
+	cr.c.buffer = nil
+	cr.rw.rw = brw.rw
+}
+
+// bufferScope runs the handler, then ends any buffering that was started while it was running.
+// Buffering started by an outer handler is left for that handler's scope to end.
+func bufferScope(h Handler) Handler {
+	return func(c *Context) (err error) {
+		if c.buffer != nil {
+			return h(c)
+		}
+		completed := false
+		defer func() {
+			if c.buffer == nil {
+				return
+			}
+			// If the handler panicked, discard the buffered response so that it can be handled as if nothing had been written.
+			c.Response.endBuffer(!completed || err != nil)
+		}()
+		err = h(c)
+		completed = true
+		return
+	}
+}
+
+// Buffered returns middleware that buffers the response of later middleware and handlers, as per ContextResponse.Buffer.
+// The buffered response is written out once they return, or discarded if they return an error or panic.
+// Applying it per route or per group allows streaming handlers to opt out of buffering.
+func Buffered() Middleware {
+	return func(next Handler) Handler {
+		return bufferScope(func(c *Context) (err error) {
+			c.Response.Buffer()
+			return next(c)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package lightwork
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errUnsatisfiableRange = errors.New("requested range is not satisfiable")
+
+// byteRange is an inclusive range of byte offsets within a stream.
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+// length returns the number of bytes covered by the range.
+func (br byteRange) length() int64 {
+	return br.end - br.start + 1
+}
+
+// parseByteRange parses the value of a Range header for a stream of the provided size.
+// If ok is false, the header should be ignored and the full stream should be sent. This is the case for malformed headers, and for multiple ranges, which aren't supported.
+// If the range can't be satisfied for the stream size, errUnsatisfiableRange is returned.
+func parseByteRange(header string, size int64) (br byteRange, ok bool, err error) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return
+	}
+	spec := strings.TrimSpace(header[len(prefix):])
+	if strings.Contains(spec, ",") {
+		return
+	}
+	dash := strings.Index(spec, "-")
+	if dash < 0 {
+		return
+	}
+	startStr, endStr := strings.TrimSpace(spec[:dash]), strings.TrimSpace(spec[dash+1:])
+
+	if startStr == "" {
+		// Suffix range, such as "bytes=-500", requesting the last N bytes.
+		suffixLen, parseErr := strconv.ParseInt(endStr, 10, 64)
+		if parseErr != nil || suffixLen < 0 {
+			return
+		}
+		if suffixLen == 0 || size == 0 {
+			return br, true, errUnsatisfiableRange
+		}
+		if suffixLen > size {
+			suffixLen = size
+		}
+		return byteRange{start: size - suffixLen, end: size - 1}, true, nil
+	}
+
+	start, parseErr := strconv.ParseInt(startStr, 10, 64)
+	if parseErr != nil || start < 0 {
+		return
+	}
+	end := size - 1
+	if endStr != "" {
+		end, parseErr = strconv.ParseInt(endStr, 10, 64)
+		if parseErr != nil || end < start {
+			return
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	if start >= size {
+		return br, true, errUnsatisfiableRange
+	}
+	return byteRange{start: start, end: end}, true, nil
+}
@@ -0,0 +1,236 @@
+package lightwork
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressMinSize is the minimum known body size for which compression is worthwhile.
+const compressMinSize = 1024
+
+// incompressibleTypePrefixes are the Content-Type prefixes of content that is typically already compressed.
+var incompressibleTypePrefixes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/x-bzip2",
+	"application/zstd",
+	"application/pdf",
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w       io.Writer
+	written int64
+}
+
+func (cw *countingWriter) Write(b []byte) (n int, err error) {
+	n, err = cw.w.Write(b)
+	cw.written += int64(n)
+	return
+}
+
+// compressResponseWriter gzips the response body, if the response is suitable for compression.
+// The decision is made when the first body bytes are written, so that the Content-Type can be inspected or inferred beforehand.
+type compressResponseWriter struct {
+	rw            http.ResponseWriter
+	cw            *countingWriter
+	gz            *gzip.Writer
+	level         int
+	statusCode    int
+	headerWritten bool
+	hijacked      bool
+}
+
+func (crw *compressResponseWriter) Header() (h http.Header) {
+	return crw.rw.Header()
+}
+
+func (crw *compressResponseWriter) WriteHeader(statusCode int) {
+	if crw.statusCode != 0 {
+		return
+	}
+	crw.statusCode = statusCode
+}
+
+func (crw *compressResponseWriter) Write(b []byte) (n int, err error) {
+	if !crw.headerWritten {
+		header := crw.Header()
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", http.DetectContentType(b))
+		}
+		crw.writeHeader(true)
+	}
+	if crw.gz != nil {
+		return crw.gz.Write(b)
+	}
+	return crw.cw.Write(b)
+}
+
+// writeHeader decides whether to compress the response, then writes the status code to the underlying writer.
+func (crw *compressResponseWriter) writeHeader(hasBody bool) {
+	crw.headerWritten = true
+	if crw.statusCode == 0 {
+		crw.statusCode = http.StatusOK
+	}
+	if hasBody && crw.shouldCompress() {
+		header := crw.Header()
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		// The level is validated when the middleware is created, so this can't fail.
+		crw.gz, _ = gzip.NewWriterLevel(crw.cw, crw.level)
+	}
+	crw.rw.WriteHeader(crw.statusCode)
+}
+
+// shouldCompress returns whether the response is suitable for compression, based on the status and headers that have been set.
+func (crw *compressResponseWriter) shouldCompress() (compress bool) {
+	switch crw.statusCode {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	header := crw.Header()
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	if cl := header.Get("Content-Length"); cl != "" {
+		size, err := strconv.ParseInt(cl, 10, 64)
+		if err == nil && size < compressMinSize {
+			return false
+		}
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range incompressibleTypePrefixes {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// Flush flushes any compressed data to the underlying writer, and flushes that too if possible.
+// If the status code hasn't been written yet, it's written first, so that the headers are sent to the client, such as for server-sent events.
+func (crw *compressResponseWriter) Flush() {
+	if crw.hijacked {
+		return
+	}
+	if !crw.headerWritten {
+		crw.writeHeader(true)
+	}
+	if crw.gz != nil {
+		crw.gz.Flush()
+	}
+	if f, ok := crw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the underlying connection, if the underlying writer supports it and the response hasn't started, after which nothing is compressed.
+func (crw *compressResponseWriter) Hijack() (conn net.Conn, rw *bufio.ReadWriter, err error) {
+	if crw.headerWritten {
+		return nil, nil, errors.New("response has already been written, so the connection can't be hijacked")
+	}
+	h, ok := crw.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err = h.Hijack()
+	if err != nil {
+		return
+	}
+	crw.hijacked = true
+	return
+}
+
+// close writes the status code if the handler never wrote a body, and finishes the gzip stream if compressing.
+func (crw *compressResponseWriter) close() (err error) {
+	if crw.hijacked {
+		return nil
+	}
+	if !crw.headerWritten {
+		if crw.statusCode == 0 {
+			return nil
+		}
+		crw.writeHeader(false)
+	}
+	if crw.gz != nil {
+		return crw.gz.Close()
+	}
+	return nil
+}
+
+// acceptsGzip returns whether the provided Accept-Encoding header value allows a gzip response.
+func acceptsGzip(acceptEncoding string) (accepted bool) {
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		accepted = true
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil && q == 0 {
+					accepted = false
+				}
+			}
+		}
+		if accepted {
+			return true
+		}
+	}
+	return false
+}
+
+// Compress returns middleware that gzips response bodies using the provided compression level, if the client accepts gzip.
+// Responses are not compressed if they're already encoded, are partial content, have a known size smaller than 1KB, or have a Content-Type that is typically already compressed, such as images.
+// The response size reported by c.Response.Size reflects the compressed size once the handler has returned.
+// Requests with an Upgrade header aren't compressed, and the connection can still be hijacked through c.EscapeHatch.
+func Compress(level int) Middleware {
+	if _, err := gzip.NewWriterLevel(io.Discard, level); err != nil {
+		panic(fmt.Sprintf("invalid gzip compression level: %d", level))
+	}
+	return func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			c.Response.Header().Add("Vary", "Accept-Encoding")
+			// Upgraded connections, such as WebSockets, take over the connection rather than writing a response body.
+			if !acceptsGzip(c.Request.Header().Get("Accept-Encoding")) || c.Request.Header().Get("Upgrade") != "" {
+				return next(c)
+			}
+
+			lrw := c.Response.rw
+			original := lrw.rw
+			crw := &compressResponseWriter{
+				rw:    original,
+				cw:    &countingWriter{w: original},
+				level: level,
+			}
+			lrw.rw = crw
+			defer func() {
+				closeErr := crw.close()
+				if closeErr != nil {
+					c.Log.Warningf("Failed to finish compressed response: %v", closeErr)
+				}
+				lrw.rw = original
+				lrw.contentLength = crw.cw.written
+			}()
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package lightwork
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ETagMode controls how ETags are generated for files.
+type ETagMode int
+
+const (
+	// ETagWeak generates a weak ETag from the file's size and modification time.
+	ETagWeak ETagMode = iota
+	// ETagStrong generates a strong ETag by hashing the file's content, which requires reading the whole file.
+	ETagStrong
+	// ETagDisabled doesn't generate an ETag. The Last-Modified header is still set.
+	ETagDisabled
+)
+
+// setFileValidators sets the Last-Modified header and, depending on the server's FileETag mode, the ETag header for the file.
+// Headers that have already been set are left as-is.
+func (cr ContextResponse) setFileValidators(file *os.File) (err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+	if !info.ModTime().IsZero() {
+		cr.setHeaderIfNotAlreadySet("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+	if cr.Header().Get("ETag") != "" {
+		return nil
+	}
+
+	switch cr.c.server.FileETag {
+	case ETagWeak:
+		cr.Header().Set("ETag", fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano()))
+	case ETagStrong:
+		currentPos, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("failed to determine file position: %w", err)
+		}
+		hash := sha256.New()
+		_, err = io.Copy(hash, file)
+		if err != nil {
+			return fmt.Errorf("failed to hash file: %w", err)
+		}
+		_, err = file.Seek(currentPos, io.SeekStart)
+		if err != nil {
+			return fmt.Errorf("failed to restore file position after hashing: %w", err)
+		}
+		cr.Header().Set("ETag", `"`+hex.EncodeToString(hash.Sum(nil)[:16])+`"`)
+	}
+	return nil
+}
+
+// etagMatches returns whether the ETag matches any in the provided header value, which is a comma-separated list of ETags, or "*".
+// If weak is true, the weak comparison is used, which ignores the W/ prefix.
+func etagMatches(header, etag string, weak bool) (matches bool) {
+	if etag == "" {
+		return false
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModifiedSince returns whether the provided Last-Modified value is no later than the provided If-Modified-Since value.
+func notModifiedSince(lastModified, ifModifiedSince string) (notModified bool) {
+	if lastModified == "" || ifModifiedSince == "" {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// isNotModified returns whether the client's cached copy is fresh, based on the request's conditional headers and the ETag and Last-Modified response headers.
+// As per RFC 7232, If-Modified-Since is ignored if If-None-Match is present, and only GET and HEAD requests are considered.
+func (cr ContextResponse) isNotModified() (notModified bool) {
+	method := cr.c.Request.Method()
+	if method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	reqHeader := cr.c.Request.Header()
+	header := cr.Header()
+	if inm := reqHeader.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, header.Get("ETag"), true)
+	}
+	return notModifiedSince(header.Get("Last-Modified"), reqHeader.Get("If-Modified-Since"))
+}
+
+// ifRangeMatches returns whether a Range request should be honoured, based on the request's If-Range header and the ETag and Last-Modified response headers.
+// If there is no If-Range header, ranges are always honoured.
+func (cr ContextResponse) ifRangeMatches() (matches bool) {
+	ifRange := strings.TrimSpace(cr.c.Request.Header().Get("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+	header := cr.Header()
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return etagMatches(ifRange, header.Get("ETag"), false)
+	}
+	lastModified := header.Get("Last-Modified")
+	return lastModified != "" && lastModified == ifRange
+}
+
+// NotModified writes a 304 status code with no body, removing the entity headers that don't apply, such as Content-Type and Content-Length.
+// It's intended to be used along with FreshWhen, to avoid generating a response that the client already has.
+func (cr ContextResponse) NotModified() (err error) {
+	header := cr.Header()
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	cr.rw.WriteHeader(http.StatusNotModified)
+	return nil
+}
+
+// StructWithETag returns the provided status code and serialised struct, along with a strong ETag computed from the serialised body.
+// The struct is serialised as per Struct, but into a buffer rather than directly to the client, so that the ETag can be computed before anything is sent.
+// If the status code is 200 and the request's If-None-Match header matches the ETag, a 304 is returned with no body instead.
+// If the ETag header has already been set, it's used as-is.
+// Since the whole body is buffered in memory, this isn't suitable for very large payloads, for which Struct should be used instead.
+func (cr ContextResponse) StructWithETag(statusCode int, s interface{}) (err error) {
+	if cr.c.server.EncodeStructPreHook != nil {
+		cr.c.server.EncodeStructPreHook(cr.c)
+	}
+	buf := bytes.Buffer{}
+	err = cr.c.server.EncodeStruct(cr.c, s, &buf)
+	if err != nil {
+		return
+	}
+	hash := sha256.Sum256(buf.Bytes())
+	cr.setHeaderIfNotAlreadySet("ETag", `"`+hex.EncodeToString(hash[:16])+`"`)
+	if statusCode == http.StatusOK && cr.isNotModified() {
+		return cr.NotModified()
+	}
+	cr.setHeaderIfNotAlreadySet("Content-Type", "application/json")
+	return cr.Bytes(statusCode, buf.Bytes())
+}
+
+// SetCacheControl sets the Cache-Control header, allowing the response to be cached for up to maxAge.
+// If public is true, shared caches such as CDNs may also cache the response. Otherwise, only the client may cache it.
+// A maxAge of 0 or less allows the response to be stored, but requires it to be revalidated before each use.
+// As with other headers, this must be called before the status code is written.
+func (cr ContextResponse) SetCacheControl(maxAge time.Duration, public bool) {
+	visibility := "private"
+	if public {
+		visibility = "public"
+	}
+	if maxAge <= 0 {
+		cr.Header().Set("Cache-Control", visibility+", no-cache")
+		return
+	}
+	cr.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int64(maxAge/time.Second)))
+}
+
+// FreshWhen sets the Last-Modified and ETag response headers to the provided validators, then returns whether the client's cached copy is still fresh, based on the request's If-None-Match and If-Modified-Since headers.
+// A zero lastModified or an empty etag is ignored. If the etag isn't quoted, it's quoted automatically, and it can be prefixed with W/ to make it weak.
+// This allows handlers to avoid expensive work when the client already has the current version:
+//
+//	if c.Request.FreshWhen(modified, etag) {
+//		return c.Response.NotModified()
+//	}
+func (cr ContextRequest) FreshWhen(lastModified time.Time, etag string) (fresh bool) {
+	header := cr.c.Response.Header()
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if etag != "" {
+		if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, `W/"`) {
+			etag = `"` + etag + `"`
+		}
+		header.Set("ETag", etag)
+	}
+	return cr.c.Response.isNotModified()
+}
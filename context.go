@@ -1,39 +1,112 @@
 package lightwork
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 )
 
 type loggingResponseWriter struct {
 	rw            http.ResponseWriter
+	log           *RequestLogger
 	statusCode    int
 	contentLength int64
+	hijacked      bool
 }
 
 func (lrw *loggingResponseWriter) Header() (h http.Header) {
 	return lrw.rw.Header()
 }
 
+// Write writes to the response body.
+// If the status code hasn't been written yet, it's recorded as 200, since that's what net/http sends implicitly, and this is logged to help track down missing status codes.
 func (lrw *loggingResponseWriter) Write(b []byte) (n int, err error) {
+	if lrw.statusCode == 0 && len(b) != 0 {
+		lrw.statusCode = http.StatusOK
+		if lrw.log != nil {
+			lrw.log.Info("Response body written before the status code, so a 200 status code was sent implicitly")
+		}
+	}
 	n, err = lrw.rw.Write(b)
 	lrw.contentLength += int64(n)
 	return
 }
 
+// WriteHeader writes the status code.
+// If a status code has already been written, net/http ignores the new one, so a warning is logged with both status codes, and the original status code is kept.
+// Informational 1xx status codes other than 101 can be written before the final status code, so they aren't recorded.
+// While the response is buffered, the status code can be changed freely.
 func (lrw *loggingResponseWriter) WriteHeader(statusCode int) {
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		lrw.rw.WriteHeader(statusCode)
+		return
+	}
+	if lrw.statusCode != 0 && !lrw.buffering() {
+		if lrw.log != nil {
+			lrw.log.Warningf("Status code %d written after status code %d had already been written, so it was ignored", statusCode, lrw.statusCode)
+		}
+		lrw.rw.WriteHeader(statusCode)
+		return
+	}
 	lrw.statusCode = statusCode
 	lrw.rw.WriteHeader(statusCode)
 }
 
+// buffering returns whether the underlying writer is buffering the response, and hasn't yet written it out.
+func (lrw *loggingResponseWriter) buffering() (buffering bool) {
+	brw, ok := lrw.rw.(*bufferedResponseWriter)
+	return ok && !brw.flushed
+}
+
+// Flush sends any buffered data to the client, if the underlying writer supports flushing.
+func (lrw *loggingResponseWriter) Flush() {
+	if f, ok := lrw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// canFlush returns whether the underlying writer supports flushing.
+func (lrw *loggingResponseWriter) canFlush() (ok bool) {
+	_, ok = lrw.rw.(http.Flusher)
+	return
+}
+
+// Hijack takes over the underlying connection, if the underlying writer supports it.
+// Once hijacked, the status code is recorded as 101 Switching Protocols if it hasn't already been set, and the framework no longer expects a response to be written.
+func (lrw *loggingResponseWriter) Hijack() (conn net.Conn, rw *bufio.ReadWriter, err error) {
+	h, ok := lrw.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err = h.Hijack()
+	if err != nil {
+		return
+	}
+	lrw.hijacked = true
+	if lrw.statusCode == 0 {
+		lrw.statusCode = http.StatusSwitchingProtocols
+	}
+	return
+}
+
+// errStreamPosition indicates that a stream's position couldn't be restored after determining its length, so it can't be safely streamed.
+var errStreamPosition = errors.New("failed to restore stream position")
+
 type Handler func(c *Context) (err error)
 
 // SimpleCtx is a simple wrapper around a context that includes the SetValue convenience function
@@ -49,6 +122,7 @@ func (ctx *SimpleCtx) SetValue(key, value interface{}) {
 // Context is the object provided to an HTTP handler.
 // It contains sub-objects for reading the request, returning a response, and logging.
 // It also includes an extended context.Context, which can be used for deadlines, cancellation, or storage of arbitrary values.
+// The context is derived from the request context, so it is cancelled when the client disconnects.
 type Context struct {
 	Context         SimpleCtx
 	Log             *RequestLogger
@@ -56,15 +130,78 @@ type Context struct {
 	Request         ContextRequest
 	server          *Server
 	escapeHatchUsed bool
+	bodyCached      bool
+	body            []byte
+	bodyErr         error
+	query           url.Values
+	paramsMap       map[string]string
+	buffer          *bufferedResponseWriter
+	onComplete      []func(c *Context)
+	spanEnd         func(status int)
 }
 
 // EscapeHatch returns the *Request and ResponseWriter for the request.
 // The use of this function assumes you need lower-level control, and thus disables some built-in functionality.
+// The ResponseWriter implements http.Hijacker, so it can be passed to WebSocket libraries to upgrade the connection.
+// Request logs are still written once the handler returns.
 func (c *Context) EscapeHatch() (rw http.ResponseWriter, req *http.Request) {
 	c.escapeHatchUsed = true
 	return c.Response.rw, c.Request.req
 }
 
+// OnComplete registers a callback to be run once the request has been completed, after any error has been handled and the response has been written, but before the request logs are written.
+// At that point, the status code and response size are final. Callbacks are run in the reverse order that they're registered, like deferred functions.
+// Callbacks are also run if the handler panics, even if the panic isn't recovered, in which case the response may be incomplete.
+func (c *Context) OnComplete(callback func(c *Context)) {
+	c.onComplete = append(c.onComplete, callback)
+}
+
+// runOnComplete runs the OnComplete callbacks in reverse order, at most once.
+func (c *Context) runOnComplete() {
+	callbacks := c.onComplete
+	c.onComplete = nil
+	for i := len(callbacks) - 1; i >= 0; i-- {
+		callbacks[i](c)
+	}
+}
+
+// StdContext returns the request's context as a standard context.Context, including any values added using SetValue, for passing to standard library and third-party functions.
+// It's derived from the request's context, unless the server's BaseContext says otherwise, so it's cancelled when the client disconnects.
+func (c *Context) StdContext() (ctx context.Context) {
+	return c.Context.Context
+}
+
+// WithTimeout replaces the request's context with one that's cancelled once the provided duration has elapsed, for scoping sub-operations.
+// The returned function cancels the derived context. The derived context remains the request's context afterwards, so values set using SetValue aren't lost.
+func (c *Context) WithTimeout(d time.Duration) (cancel context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(c.Context.Context, d)
+	c.Context.Context = ctx
+	return cancel
+}
+
+// WithCancel replaces the request's context with one that can be cancelled, for scoping sub-operations.
+// As with WithTimeout, the returned function cancels the derived context, which remains the request's context afterwards.
+func (c *Context) WithCancel() (cancel context.CancelFunc) {
+	ctx, cancel := context.WithCancel(c.Context.Context)
+	c.Context.Context = ctx
+	return cancel
+}
+
+// Deadline returns the time when the request's context will be cancelled, and whether a deadline is set.
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.Context.Deadline()
+}
+
+// TimeRemaining returns the time left before the request's context deadline, which is negative if the deadline has passed.
+// If no deadline is set, the maximum Duration is returned.
+func (c *Context) TimeRemaining() (remaining time.Duration) {
+	deadline, ok := c.Deadline()
+	if !ok {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Until(deadline)
+}
+
 // ContextResponse contains the methods used to return an HTTP response
 type ContextResponse struct {
 	rw *loggingResponseWriter
@@ -98,6 +235,42 @@ func (cr ContextResponse) Status(statusCode int) (err error) {
 	return nil
 }
 
+// NoContent returns a 204 status code, with no response body.
+func (cr ContextResponse) NoContent() (err error) {
+	return cr.Status(http.StatusNoContent)
+}
+
+// Accepted returns a 202 status code, with no response body.
+func (cr ContextResponse) Accepted() (err error) {
+	return cr.Status(http.StatusAccepted)
+}
+
+// Created returns a 201 status code, with the Location header set to the provided location, and a serialised struct as per Struct.
+func (cr ContextResponse) Created(location string, s interface{}) (err error) {
+	cr.Header().Set("Location", location)
+	return cr.Struct(http.StatusCreated, s)
+}
+
+// Redirect returns the provided status code, with the Location header set to the provided location, and no response body.
+// The status code must be in the 3xx range.
+func (cr ContextResponse) Redirect(statusCode int, location string) (err error) {
+	if statusCode < 300 || statusCode > 399 {
+		return fmt.Errorf("invalid redirect status code: %d", statusCode)
+	}
+	cr.Header().Set("Location", location)
+	return cr.Status(statusCode)
+}
+
+// RedirectPermanent redirects to the provided location using a 308 status code, which preserves the request method.
+func (cr ContextResponse) RedirectPermanent(location string) (err error) {
+	return cr.Redirect(http.StatusPermanentRedirect, location)
+}
+
+// RedirectTemporary redirects to the provided location using a 307 status code, which preserves the request method.
+func (cr ContextResponse) RedirectTemporary(location string) (err error) {
+	return cr.Redirect(http.StatusTemporaryRedirect, location)
+}
+
 // Bytes returns the provided status code and body.
 // If the Content-Type header is not already set, it will be set to application/octet-stream.
 func (cr ContextResponse) Bytes(statusCode int, body []byte) (err error) {
@@ -118,12 +291,60 @@ func (cr ContextResponse) String(statusCode int, body string) (err error) {
 	return cr.Bytes(statusCode, []byte(body))
 }
 
+// structWriter delays writing the status code until the struct encoder first writes to it.
+// This gives the encoder a chance to set headers before they're sent.
+type structWriter struct {
+	cr          ContextResponse
+	statusCode  int
+	contentType string
+	wroteHeader bool
+}
+
+func (sw *structWriter) Write(b []byte) (n int, err error) {
+	sw.writeHeader()
+	return sw.cr.rw.Write(b)
+}
+
+// writeHeader sets the default Content-Type if necessary, then writes the status code, if it hasn't already been written.
+func (sw *structWriter) writeHeader() {
+	if sw.wroteHeader {
+		return
+	}
+	sw.wroteHeader = true
+	sw.cr.setHeaderIfNotAlreadySet("Content-Type", sw.contentType)
+	sw.cr.rw.WriteHeader(sw.statusCode)
+}
+
 // Struct returns the provided status code, and a serialised struct
-// The struct will be serialised using the server's configured StructEncoder.
+// The struct will be serialised using the server's configured EncodeStruct function, after calling the EncodeStructPreHook, if configured.
+// The status code isn't written until the encoder first writes to the body, so the pre-hook and the encoder can both set headers.
+// If the Content-Type header still isn't set at that point, it will be set to application/json.
+// If the encoder fails before writing anything, the status code isn't written, so that an error response can still be written.
 func (cr ContextResponse) Struct(statusCode int, s interface{}) (err error) {
-	cr.c.server.EncodeStructPreHook(cr.c)
-	cr.rw.WriteHeader(statusCode)
-	return cr.c.server.EncodeStruct(cr.c, s, cr.rw)
+	if cr.c.server.EncodeStructPreHook != nil {
+		cr.c.server.EncodeStructPreHook(cr.c)
+	}
+	sw := &structWriter{cr: cr, statusCode: statusCode, contentType: "application/json"}
+	err = cr.c.server.EncodeStruct(cr.c, s, sw)
+	if err != nil {
+		return
+	}
+	sw.writeHeader()
+	return
+}
+
+// XML returns the provided status code, and the provided value serialised as XML using encoding/xml.
+// This is independent of the server's configured EncodeStruct function.
+// If the Content-Type header is not already set, it will be set to application/xml.
+// As with Struct, the status code isn't written if the encoder fails before writing anything.
+func (cr ContextResponse) XML(statusCode int, s interface{}) (err error) {
+	sw := &structWriter{cr: cr, statusCode: statusCode, contentType: "application/xml"}
+	err = xml.NewEncoder(sw).Encode(s)
+	if err != nil {
+		return
+	}
+	sw.writeHeader()
+	return
 }
 
 // Stream returns the provided status code, then streams the provided Reader as the body.
@@ -131,41 +352,114 @@ func (cr ContextResponse) Struct(statusCode int, s interface{}) (err error) {
 // If you don't want Go to infer the Content-Type, you should explicitly set the header BEFORE using this function.
 // For relatively short streams, Go will automatically buffer the output and set the Content-Length after reading the full stream.
 // For longer streams, Go will use chunked encoding.
+// The status code isn't written until the first read from the stream succeeds, so if the stream fails immediately, an error response can still be written.
+// If the stream fails after that, the error is returned, but the response can no longer be changed.
 func (cr ContextResponse) Stream(statusCode int, stream io.Reader) (err error) {
+	buf := make([]byte, 32*1024)
+	var n int
+	for n == 0 && err == nil {
+		n, err = stream.Read(buf)
+	}
+	if err != nil && err != io.EOF && n == 0 {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+
 	cr.rw.WriteHeader(statusCode)
-	_, err = io.Copy(cr.rw, stream)
-	return
+	if n > 0 {
+		_, writeErr := cr.rw.Write(buf[:n])
+		if writeErr != nil {
+			return fmt.Errorf("failed to write body: %w", writeErr)
+		}
+	}
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read stream after response started: %w", err)
+	}
+	_, err = io.CopyBuffer(cr.rw, stream, buf)
+	if err != nil {
+		return fmt.Errorf("failed to stream body after response started: %w", err)
+	}
+	return nil
 }
 
-// StreamReadSeeker returns the provided status code, then streams the provided ReadSeeker as the body.
-// Go will automatically set the Content-Type based on the first 512 bytes of the stream, if the header is not already set.
-// If you don't want Go to infer the Content-Type, you should explicitly set the header BEFORE using this function.
-func (cr ContextResponse) StreamReadSeeker(statusCode int, stream io.ReadSeeker) (err error) {
-	currentPos, err := stream.Seek(0, io.SeekCurrent)
+// streamLen returns the current position of the stream, and the number of bytes remaining after it.
+// Streams that report their unread length, such as bytes.Reader and strings.Reader, only need to be seeked once.
+func (cr ContextResponse) streamLen(stream io.ReadSeeker) (currentPos, remaining int64, err error) {
+	currentPos, err = stream.Seek(0, io.SeekCurrent)
 	if err != nil {
 		cr.c.Log.Warningf("Unable to determine current stream position: %v", err)
-		cr.c.Log.Info("Falling back to chunked streaming")
-		return cr.Stream(statusCode, stream)
+		return
+	}
+	if lr, ok := stream.(interface{ Len() int }); ok {
+		return currentPos, int64(lr.Len()), nil
 	}
+
 	totalStreamLen, err := stream.Seek(0, io.SeekEnd)
 	if err != nil {
 		cr.c.Log.Warningf("Unable to determine total stream length: %v", err)
-		cr.c.Log.Info("Falling back to chunked streaming")
-		return cr.Stream(statusCode, stream)
+		return
 	}
 	_, err = stream.Seek(currentPos, io.SeekStart)
 	if err != nil {
 		cr.c.Log.Errorf("Unable to restore stream position after reading length: %v", err)
+		return 0, 0, errStreamPosition
+	}
+	return currentPos, totalStreamLen - currentPos, nil
+}
+
+// StreamReadSeeker returns the provided status code, then streams the provided ReadSeeker as the body.
+// If the status code is 200 and the request includes a single byte range, only that range is streamed, with a 206 status code.
+// If the request includes an If-Range header, the range is only honoured if it matches the ETag or Last-Modified response header.
+// Go will automatically set the Content-Type based on the first 512 bytes of the stream, if the header is not already set.
+// If you don't want Go to infer the Content-Type, you should explicitly set the header BEFORE using this function.
+func (cr ContextResponse) StreamReadSeeker(statusCode int, stream io.ReadSeeker) (err error) {
+	currentPos, streamLen, err := cr.streamLen(stream)
+	if err == errStreamPosition {
 		return fmt.Errorf("failed to safely determine stream length - aborting")
 	}
+	if err != nil || streamLen < 0 {
+		cr.c.Log.Info("Falling back to chunked streaming")
+		return cr.Stream(statusCode, stream)
+	}
+	if streamLen == 0 {
+		cr.Header().Set("Content-Length", "0")
+		cr.rw.WriteHeader(statusCode)
+		return nil
+	}
 
-	cr.Header().Set("Content-Length", strconv.FormatInt(totalStreamLen-currentPos, 10))
-	return cr.Stream(statusCode, stream)
+	cr.Header().Set("Accept-Ranges", "bytes")
+	rangeHeader := cr.c.Request.Header().Get("Range")
+	if statusCode != http.StatusOK || rangeHeader == "" || !cr.ifRangeMatches() {
+		cr.Header().Set("Content-Length", strconv.FormatInt(streamLen, 10))
+		return cr.Stream(statusCode, stream)
+	}
+
+	br, ok, err := parseByteRange(rangeHeader, streamLen)
+	if err != nil {
+		cr.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", streamLen))
+		return cr.Status(http.StatusRequestedRangeNotSatisfiable)
+	}
+	if !ok {
+		cr.Header().Set("Content-Length", strconv.FormatInt(streamLen, 10))
+		return cr.Stream(statusCode, stream)
+	}
+
+	_, err = stream.Seek(currentPos+br.start, io.SeekStart)
+	if err != nil {
+		cr.c.Log.Errorf("Unable to seek to start of requested range: %v", err)
+		return fmt.Errorf("failed to seek to requested range - aborting")
+	}
+	cr.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", br.start, br.end, streamLen))
+	cr.Header().Set("Content-Length", strconv.FormatInt(br.length(), 10))
+	return cr.Stream(http.StatusPartialContent, io.LimitReader(stream, br.length()))
 }
 
 // File returns the provided status code, then streams the provided file as the body.
 // Go will automatically set the Content-Type based on the first 512 bytes of the file, if the header is not already set.
 // If you don't want Go to infer the Content-Type, you should explicitly set the header BEFORE using this function.
+// For 200 responses, the Last-Modified and ETag headers are set as per the server's FileETag mode, and conditional GET and HEAD requests are responded to with a 304 when the client's copy is fresh.
 func (cr ContextResponse) File(statusCode int, filename string) (err error) {
 	file, err := os.Open(filename)
 	if err != nil {
@@ -173,9 +467,38 @@ func (cr ContextResponse) File(statusCode int, filename string) (err error) {
 	}
 	defer file.Close()
 
+	if statusCode == http.StatusOK {
+		err = cr.setFileValidators(file)
+		if err != nil {
+			return
+		}
+		if cr.isNotModified() {
+			return cr.NotModified()
+		}
+	}
+
 	return cr.StreamReadSeeker(statusCode, file)
 }
 
+// Flush sends any buffered response data to the client, if the underlying writer supports flushing.
+// This is useful for long-lived streaming responses.
+func (cr ContextResponse) Flush() {
+	cr.rw.Flush()
+}
+
+// SetTrailer declares the named header as a trailer, which will be sent after the response body.
+// This must be called before the status code is written. Once the body has been written, the trailer's value can be set using Header().Set, as per net/http's trailer conventions.
+// Trailers are only sent for chunked HTTP/1.1 responses and HTTP/2 responses, so they're mostly useful with Stream and SSE.
+func (cr ContextResponse) SetTrailer(key string) {
+	cr.Header().Add("Trailer", http.CanonicalHeaderKey(key))
+}
+
+// SetCookie adds a Set-Cookie header to the response.
+// As with other headers, this must be called before the status code is written.
+func (cr ContextResponse) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(cr.rw, cookie)
+}
+
 // GetStatusCode returns the status code that was sent in the response.
 // If a response code has not been sent yet, this will return 0.
 func (cr ContextResponse) GetStatusCode() (statusCode int) {
@@ -187,13 +510,96 @@ type ContextRequest struct {
 	c      *Context
 	req    *http.Request
 	params httprouter.Params
+	route  string
 }
 
 // ClientHost returns the hostname or IP address of the client making the request.
+// This uses the server's ClientHost function if configured, otherwise it returns the result of ClientIP.
 func (cr ContextRequest) ClientHost() (host string) {
+	if cr.c.server.ClientHost == nil {
+		ip := cr.ClientIP()
+		if ip == nil {
+			return ""
+		}
+		return ip.String()
+	}
 	return cr.c.server.ClientHost(cr.c)
 }
 
+// RemoteIP returns the IP address of the direct connection that made the request, ignoring any proxy headers.
+// It returns nil if the address can't be parsed.
+func (cr ContextRequest) RemoteIP() (ip net.IP) {
+	host, _, err := net.SplitHostPort(cr.req.RemoteAddr)
+	if err != nil {
+		host = cr.req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// ClientIP returns the IP address of the client making the request.
+// If the server's TrustProxyHeaders is enabled, this uses X-Forwarded-For, or X-Real-IP if that isn't present.
+// X-Forwarded-For is read from right to left, since each proxy appends the address it received the request from, whereas entries further left may have been set by the client.
+// The first valid address that isn't within the server's TrustedProxies is used. If TrustedProxies is empty, this is the right-most valid address.
+// Otherwise, or if neither header contains a valid address, it's the same as RemoteIP.
+func (cr ContextRequest) ClientIP() (ip net.IP) {
+	if cr.c.server.TrustProxyHeaders {
+		header := cr.Header()
+		hops := make([]string, 0)
+		for _, v := range header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := net.ParseIP(strings.TrimSpace(hops[i]))
+			if hop == nil {
+				continue
+			}
+			ip = hop
+			if !cr.c.server.isTrustedProxy(ip) {
+				return ip
+			}
+		}
+		// Every address belonged to a trusted proxy, so the left-most is the closest to the client.
+		if ip != nil {
+			return ip
+		}
+		ip = net.ParseIP(strings.TrimSpace(header.Get("X-Real-IP")))
+		if ip != nil {
+			return ip
+		}
+	}
+	return cr.RemoteIP()
+}
+
+// isTrustedProxy returns whether the IP address is within the server's TrustedProxies.
+// If TrustedProxies is empty, no addresses are trusted.
+func (s *Server) isTrustedProxy(ip net.IP) (trusted bool) {
+	for _, network := range s.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scheme returns the URL scheme used by the client to make the request, either "https" or "http".
+// If the server's TrustProxyHeaders is enabled, this honours the X-Forwarded-Proto header.
+func (cr ContextRequest) Scheme() (scheme string) {
+	if cr.c.server.TrustProxyHeaders {
+		proto := cr.Header().Get("X-Forwarded-Proto")
+		if comma := strings.Index(proto, ","); comma >= 0 {
+			proto = proto[:comma]
+		}
+		proto = strings.ToLower(strings.TrimSpace(proto))
+		if proto == "https" || proto == "http" {
+			return proto
+		}
+	}
+	if cr.req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
 // Method returns the HTTP method of the request.
 func (cr ContextRequest) Method() (m string) {
 	return cr.req.Method
@@ -208,44 +614,280 @@ func (cr ContextRequest) Header() (h *http.Header) {
 	return &cr.req.Header
 }
 
+// IsWebSocketUpgrade returns whether the request is asking to upgrade the connection to a WebSocket.
+func (cr ContextRequest) IsWebSocketUpgrade() (upgrade bool) {
+	header := cr.Header()
+	if !strings.EqualFold(strings.TrimSpace(header.Get("Upgrade")), "websocket") {
+		return false
+	}
+	for _, v := range header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Cookie returns the named cookie provided in the request, or http.ErrNoCookie if it isn't present.
+func (cr ContextRequest) Cookie(name string) (cookie *http.Cookie, err error) {
+	return cr.req.Cookie(name)
+}
+
+// Cookies returns all the cookies provided in the request.
+func (cr ContextRequest) Cookies() (cookies []*http.Cookie) {
+	return cr.req.Cookies()
+}
+
 // Params returns the httprouter.Params object for the request.
 func (cr ContextRequest) Params() (p httprouter.Params) {
 	return cr.params
 }
 
+// RoutePattern returns the pattern of the route that matched the request, such as /users/:id.
+// If the request didn't match a route, such as in the NotFound handler, this returns an empty string.
+func (cr ContextRequest) RoutePattern() (pattern string) {
+	return cr.route
+}
+
+// ParamsMap returns all the path parameters of the request as a map of names to values.
+// The map is built on first use, and cached on the Context for subsequent calls, so it shouldn't be modified.
+func (cr ContextRequest) ParamsMap() (params map[string]string) {
+	if cr.c.paramsMap == nil {
+		cr.c.paramsMap = make(map[string]string, len(cr.params))
+		for _, p := range cr.params {
+			cr.c.paramsMap[p.Key] = p.Value
+		}
+	}
+	return cr.c.paramsMap
+}
+
 // GetParam is shorthand for Params().ByName.
 func (cr ContextRequest) GetParam(name string) (value string) {
 	return cr.params.ByName(name)
 }
 
+// queryValues returns the parsed query string of the request URL.
+// The query is parsed on first use, and cached on the Context for subsequent calls.
+func (cr ContextRequest) queryValues() (values url.Values) {
+	if cr.c.query == nil {
+		cr.c.query = cr.req.URL.Query()
+	}
+	return cr.c.query
+}
+
+// Query returns the first value of the named query parameter, or an empty string if it isn't present.
+func (cr ContextRequest) Query(name string) (value string) {
+	return cr.queryValues().Get(name)
+}
+
+// QueryDefault returns the first value of the named query parameter, or the provided fallback if it isn't present.
+// A parameter that is present but empty is returned as an empty string, not the fallback.
+func (cr ContextRequest) QueryDefault(name, fallback string) (value string) {
+	if !cr.HasQuery(name) {
+		return fallback
+	}
+	return cr.Query(name)
+}
+
+// QueryValues returns all the values of the named query parameter.
+func (cr ContextRequest) QueryValues(name string) (values []string) {
+	return cr.queryValues()[name]
+}
+
+// HasQuery returns whether the named query parameter is present, even if it has an empty value.
+func (cr ContextRequest) HasQuery(name string) (present bool) {
+	_, present = cr.queryValues()[name]
+	return
+}
+
+// ParamInt parses the named path parameter as an int.
+func (cr ContextRequest) ParamInt(name string) (value int, err error) {
+	value, err = strconv.Atoi(cr.GetParam(name))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse path parameter %q as int: %w", name, err)
+	}
+	return
+}
+
+// QueryInt parses the named query parameter as an int.
+// If the parameter is missing or can't be parsed, the provided fallback is returned.
+func (cr ContextRequest) QueryInt(name string, fallback int) (value int) {
+	value, err := strconv.Atoi(cr.Query(name))
+	if err != nil {
+		return fallback
+	}
+	return
+}
+
+// QueryBool parses the named query parameter as a bool, using the same rules as strconv.ParseBool.
+func (cr ContextRequest) QueryBool(name string) (value bool, err error) {
+	value, err = strconv.ParseBool(cr.Query(name))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse query parameter %q as bool: %w", name, err)
+	}
+	return
+}
+
+// QueryFloat parses the named query parameter as a float64.
+func (cr ContextRequest) QueryFloat(name string) (value float64, err error) {
+	value, err = strconv.ParseFloat(cr.Query(name), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse query parameter %q as float: %w", name, err)
+	}
+	return
+}
+
 // BodyStream returns the body of the request as a io.ReadCloser.
+// If the server's MaxBodyBytes is set, reading beyond the limit will return ErrBodyTooLarge.
+// If the body has already been read in full and cached by one of the other Body methods, a new reader over the cached body is returned, so that it can be read again.
 func (cr ContextRequest) BodyStream() (stream io.ReadCloser) {
+	if cr.c.bodyCached {
+		cr.req.Body = &cachedBody{r: bytes.NewReader(cr.c.body), err: cr.c.bodyErr}
+		return cr.req.Body
+	}
 	return cr.req.Body
 }
 
+// cacheBody reads the rest of the body and caches it, if it hasn't already been cached, so that later calls can read it again.
+// Any error encountered while reading, such as ErrBodyTooLarge, is cached along with it.
+func (cr ContextRequest) cacheBody() (body []byte, err error) {
+	if !cr.c.bodyCached {
+		buf := bytes.Buffer{}
+		bodyStream := cr.BodyStream()
+		_, err = buf.ReadFrom(bodyStream)
+		bodyStream.Close()
+		cr.c.body, cr.c.bodyErr, cr.c.bodyCached = buf.Bytes(), err, true
+	}
+	return cr.c.body, cr.c.bodyErr
+}
+
+// cachedBodyStream caches the body if needed, then returns a new reader over the cached body.
+func (cr ContextRequest) cachedBodyStream() (stream io.ReadCloser) {
+	cr.cacheBody()
+	return cr.BodyStream()
+}
+
+// ResetBody rewinds the body, so that it can be read again from the start, such as after reading it from BodyStream, or from the *http.Request returned by EscapeHatch.
+// If the body hasn't been cached yet, the rest of it is read and cached first. Anything that was already read from BodyStream before then can't be recovered.
+func (cr ContextRequest) ResetBody() {
+	cr.cachedBodyStream()
+}
+
 // BodyBytes returns the body of the request as a byte slice.
+// The body is cached, so it can be read again by later calls to BodyBytes, BodyStruct, and the other Body methods. The returned slice is shared between calls, so it shouldn't be modified.
+// If the body can't be read in full, such as when it exceeds the server's MaxBodyBytes, the error is logged and nil is returned, rather than a truncated body. Use BodyBytesLimited if you need to handle the error.
 func (cr ContextRequest) BodyBytes() (body []byte) {
+	body, err := cr.cacheBody()
+	if err != nil {
+		cr.c.Log.Errorf("Failed to read request body: %v", err)
+		return nil
+	}
+	return
+}
+
+// BodyBytesLimited returns the body of the request as a byte slice, or ErrBodyTooLarge if it exceeds the provided maximum number of bytes.
+// The server's MaxBodyBytes still applies, if it's lower.
+// As with BodyBytes, the body is cached if it's read successfully.
+// If it isn't, the bytes that were read are put back, so the body can still be read from the start using the other Body methods, such as to read it using a higher limit.
+func (cr ContextRequest) BodyBytesLimited(max int64) (body []byte, err error) {
+	if cr.c.bodyCached {
+		if cr.c.bodyErr != nil {
+			return nil, cr.c.bodyErr
+		}
+		if int64(len(cr.c.body)) > max {
+			return nil, ErrBodyTooLarge
+		}
+		return cr.c.body, nil
+	}
+
 	buf := bytes.Buffer{}
 	bodyStream := cr.BodyStream()
-	defer bodyStream.Close()
-	buf.ReadFrom(bodyStream)
-	return buf.Bytes()
+	// Read one byte more than allowed, in order to detect when the limit has been exceeded.
+	_, err = buf.ReadFrom(io.LimitReader(bodyStream, max+1))
+	if err == nil && int64(buf.Len()) > max {
+		err = ErrBodyTooLarge
+	}
+	if err != nil {
+		cr.req.Body = &replayedBody{r: io.MultiReader(bytes.NewReader(buf.Bytes()), bodyStream), rc: bodyStream}
+		return nil, err
+	}
+	bodyStream.Close()
+	cr.c.body, cr.c.bodyCached = buf.Bytes(), true
+	return cr.c.body, nil
 }
 
 // BodyString returns the body of the request as a string.
+// As with BodyBytes, an empty string is returned if the body can't be read in full.
 func (cr ContextRequest) BodyString() (body string) {
 	return string(cr.BodyBytes())
 }
 
+// FormValue returns the first value of the named field from the form body or query string, as per http.Request.FormValue.
+// URL-encoded form bodies are cached, as with BodyBytes, so the body can be read again afterwards. Multipart form bodies are consumed while parsing, so they can't be read again using the other Body methods.
+func (cr ContextRequest) FormValue(name string) (value string) {
+	if cr.checkContentType("multipart/form-data") != nil {
+		cr.cachedBodyStream()
+	}
+	return cr.req.FormValue(name)
+}
+
+// FormFile returns the first file uploaded in the named field of a multipart form body, as per http.Request.FormFile.
+// The body is consumed while parsing, so it can't be read again using the other Body methods afterwards.
+func (cr ContextRequest) FormFile(name string) (file multipart.File, header *multipart.FileHeader, err error) {
+	return cr.req.FormFile(name)
+}
+
+// MultipartForm parses the multipart form body of the request, storing up to maxMemory bytes of file parts in memory, and the rest on disk.
+// The body is consumed while parsing, so it can't be read again using the other Body methods afterwards.
+func (cr ContextRequest) MultipartForm(maxMemory int64) (form *multipart.Form, err error) {
+	err = cr.req.ParseMultipartForm(maxMemory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse multipart form: %w", err)
+	}
+	return cr.req.MultipartForm, nil
+}
+
 // BodyStruct reads and deserialises the body of the request into the provided struct.
-// If the deserialisation is successful, it also runs the provided validation function, and returns the resulting error, if present.
+// If the deserialisation is successful, it also runs the configured validation function, and returns the resulting error, if present.
 // The result parameter must be a pointer to a struct.
+// This is equivalent to BodyStructValidated.
 func (cr ContextRequest) BodyStruct(result interface{}) (err error) {
-	bodyStream := cr.BodyStream()
+	return cr.BodyStructValidated(result)
+}
+
+// BodyXML reads and deserialises the XML body of the request into the provided value, using encoding/xml.
+// This is independent of the server's configured DecodeStruct function, and doesn't run validation.
+// The result parameter must be a pointer. As with BodyBytes, the body is cached, so it can be read again afterwards.
+func (cr ContextRequest) BodyXML(result interface{}) (err error) {
+	return xml.NewDecoder(cr.cachedBodyStream()).Decode(result)
+}
+
+// BodyStructValidated reads and deserialises the body of the request into the provided struct, then validates it using the server's ValidateStruct function, if one is configured.
+// Validation failures are wrapped so that errors.Is(err, ErrValidation) can be used to distinguish them from deserialisation failures.
+// The result parameter must be a pointer to a struct.
+// As with BodyBytes, the body is cached, so it can be read again afterwards.
+// If the server's ExpectedRequestContentType is set, an error matching ErrUnsupportedMediaType is returned if the request's Content-Type doesn't match it, without reading the body.
+func (cr ContextRequest) BodyStructValidated(result interface{}) (err error) {
+	if cr.c.server.ExpectedRequestContentType != "" {
+		err = cr.checkContentType(cr.c.server.ExpectedRequestContentType)
+		if err != nil {
+			return
+		}
+	}
+	bodyStream := cr.cachedBodyStream()
 	err = cr.c.server.DecodeStruct(cr.c, bodyStream, result)
 	if err != nil {
 		return
 	}
 
-	return cr.c.server.ValidateStruct(cr.c, result)
+	if cr.c.server.ValidateStruct == nil {
+		return nil
+	}
+	err = cr.c.server.ValidateStruct(cr.c, result)
+	if err != nil {
+		return validationError{err: err}
+	}
+	return nil
 }
@@ -0,0 +1,30 @@
+package lightwork
+
+// contextKey is a typed key for storing values on a Context.
+// Each key created by ContextKey is distinct, even if another key has the same name and type, so keys from different packages can't collide.
+type contextKey[T any] struct {
+	name *string
+}
+
+// ContextKey returns a new typed key, which can be used to store and retrieve values of type T on a Context.
+// The name is only used for debugging. Keys should typically be created once, and stored in a package-level variable.
+func ContextKey[T any](name string) contextKey[T] {
+	return contextKey[T]{name: &name}
+}
+
+// String returns the name of the key.
+func (k contextKey[T]) String() string {
+	return *k.name
+}
+
+// Set stores the value on the Context under this key, using SetValue.
+func (k contextKey[T]) Set(c *Context, value T) {
+	c.Context.SetValue(k, value)
+}
+
+// Get retrieves the value stored on the Context under this key.
+// If no value is stored, the zero value of T and false are returned.
+func (k contextKey[T]) Get(c *Context) (value T, ok bool) {
+	value, ok = c.Context.Value(k).(T)
+	return
+}
@@ -0,0 +1,272 @@
+package lightwork
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextCancelledWhenRequestCancelled(t *testing.T) {
+	s := newTestServer()
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	s.GetHandlerGroup("").GET("/wait", func(c *Context) (err error) {
+		close(started)
+		select {
+		case <-c.Context.Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+		}
+		return c.Response.Status(http.StatusOK)
+	})
+	ts := s.StartTest()
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/wait", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		<-started
+		cancel()
+	}()
+	resp, err := http.DefaultClient.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the request to be cancelled")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler context wasn't cancelled when the request was cancelled")
+	}
+}
+
+// failingReader returns its data, then fails with err.
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (fr *failingReader) Read(b []byte) (n int, err error) {
+	if len(fr.data) == 0 {
+		return 0, fr.err
+	}
+	n = copy(b, fr.data)
+	fr.data = fr.data[n:]
+	return
+}
+
+// readSeeker hides any Len method of the wrapped stream, so that its length has to be found by seeking.
+type readSeeker struct {
+	io.ReadSeeker
+}
+
+// serveStream serves a GET request using the handler, returning the recorded response and the handler's error.
+func serveStream(t *testing.T, h Handler) (rec *headerCountingRecorder, err error) {
+	t.Helper()
+	s := newTestServer()
+	s.GetHandlerGroup("").GET("/stream", func(c *Context) error {
+		err = h(c)
+		return err
+	})
+	rec = serveTest(s, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	return
+}
+
+func TestStreamEmptyReader(t *testing.T) {
+	rec, err := serveStream(t, func(c *Context) error {
+		return c.Response.Stream(http.StatusOK, strings.NewReader(""))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Fatalf("expected an empty 200, got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+	if rec.writeHeaderCalls != 1 {
+		t.Fatalf("expected WriteHeader to be called once, got %d", rec.writeHeaderCalls)
+	}
+}
+
+func TestStreamReaderFailsImmediately(t *testing.T) {
+	readErr := errors.New("read failed")
+	rec, err := serveStream(t, func(c *Context) error {
+		streamErr := c.Response.Stream(http.StatusOK, &failingReader{err: readErr})
+		if !errors.Is(streamErr, readErr) {
+			t.Errorf("expected the read error, got %v", streamErr)
+		}
+		return c.Response.Status(http.StatusBadGateway)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected the error response to be written, got %d", rec.Code)
+	}
+}
+
+func TestStreamReaderFailsAfterResponseStarted(t *testing.T) {
+	readErr := errors.New("read failed")
+	rec, err := serveStream(t, func(c *Context) error {
+		return c.Response.Stream(http.StatusOK, &failingReader{data: []byte("0123456789"), err: readErr})
+	})
+	if !errors.Is(err, readErr) {
+		t.Fatalf("expected the read error, got %v", err)
+	}
+	if rec.writeHeaderCalls != 1 {
+		t.Fatalf("expected WriteHeader to be called once, got %d", rec.writeHeaderCalls)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "0123456789" {
+		t.Fatalf("expected the partial body with a 200, got %d with %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStreamReadSeekerEmpty(t *testing.T) {
+	rec, err := serveStream(t, func(c *Context) error {
+		return c.Response.StreamReadSeeker(http.StatusOK, bytes.NewReader(nil))
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cl := rec.Header().Get("Content-Length"); cl != "0" {
+		t.Fatalf("expected a Content-Length of 0, got %q", cl)
+	}
+	if rec.writeHeaderCalls != 1 {
+		t.Fatalf("expected WriteHeader to be called once, got %d", rec.writeHeaderCalls)
+	}
+}
+
+func TestStreamReadSeekerOffset(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream func(r *bytes.Reader) io.ReadSeeker
+	}{
+		{"Len", func(r *bytes.Reader) io.ReadSeeker { return r }},
+		{"Seek", func(r *bytes.Reader) io.ReadSeeker { return readSeeker{r} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader([]byte("0123456789"))
+			if _, err := r.Seek(4, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			rec, err := serveStream(t, func(c *Context) error {
+				return c.Response.StreamReadSeeker(http.StatusOK, tt.stream(r))
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cl := rec.Header().Get("Content-Length"); cl != "6" {
+				t.Fatalf("expected a Content-Length of 6, got %q", cl)
+			}
+			if rec.Body.String() != "456789" {
+				t.Fatalf("expected the remaining bytes, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestBodyReadByMiddlewareAndHandler(t *testing.T) {
+	s := newTestServer()
+	var logged string
+	hg := s.GetHandlerGroup("")
+	hg.AddMiddleware(func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			logged = string(c.Request.BodyBytes())
+			c.Log.Infof("Request body: %s", logged)
+			return next(c)
+		}
+	})
+	var body struct {
+		Name string `json:"name"`
+	}
+	hg.POST("/", func(c *Context) (err error) {
+		err = c.Request.BodyStruct(&body)
+		if err != nil {
+			return
+		}
+		return c.Response.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"lightwork"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := serveTest(s, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected a 204, got %d", rec.Code)
+	}
+	if logged != `{"name":"lightwork"}` {
+		t.Fatalf("expected the middleware to read the full body, got %q", logged)
+	}
+	if body.Name != "lightwork" {
+		t.Fatalf("expected the handler to decode the body, got %q", body.Name)
+	}
+}
+
+func TestBodyBytesExceedsMaxBodyBytes(t *testing.T) {
+	s := newTestServer()
+	s.MaxBodyBytes = 4
+	var body []byte
+	var limitedErr error
+	s.GetHandlerGroup("").POST("/", func(c *Context) (err error) {
+		body = c.Request.BodyBytes()
+		_, limitedErr = c.Request.BodyBytesLimited(100)
+		return c.Response.Status(http.StatusNoContent)
+	})
+
+	serveTest(s, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789")))
+	if body != nil {
+		t.Fatalf("expected no body rather than a truncated one, got %q", body)
+	}
+	if !errors.Is(limitedErr, ErrBodyTooLarge) {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", limitedErr)
+	}
+}
+
+func TestBodyBytesLimitedRestoresBody(t *testing.T) {
+	s := newTestServer()
+	var limitedErr error
+	var body string
+	s.GetHandlerGroup("").POST("/", func(c *Context) (err error) {
+		_, limitedErr = c.Request.BodyBytesLimited(4)
+		body = c.Request.BodyString()
+		return c.Response.Status(http.StatusNoContent)
+	})
+
+	serveTest(s, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789")))
+	if !errors.Is(limitedErr, ErrBodyTooLarge) {
+		t.Fatalf("expected ErrBodyTooLarge, got %v", limitedErr)
+	}
+	if body != "0123456789" {
+		t.Fatalf("expected the full body to be readable after exceeding the limit, got %q", body)
+	}
+}
+
+func TestFormValueKeepsBody(t *testing.T) {
+	s := newTestServer()
+	var value, body string
+	s.GetHandlerGroup("").POST("/", func(c *Context) (err error) {
+		value = c.Request.FormValue("name")
+		body = c.Request.BodyString()
+		return c.Response.Status(http.StatusNoContent)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=lightwork"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	serveTest(s, req)
+	if value != "lightwork" {
+		t.Fatalf("expected the form value, got %q", value)
+	}
+	if body != "name=lightwork" {
+		t.Fatalf("expected the body to still be readable, got %q", body)
+	}
+}
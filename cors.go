@@ -0,0 +1,103 @@
+package lightwork
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures the CORS middleware.
+type CORSOptions struct {
+	// AllowedOrigins is the list of origins that may make cross-origin requests.
+	// The "*" wildcard allows any origin, unless AllowCredentials is enabled, in which case it's ignored.
+	AllowedOrigins []string
+	// AllowedMethods is the list of methods allowed in preflight requests.
+	// If empty, the common methods GET, HEAD, POST, PUT, PATCH, and DELETE are allowed.
+	AllowedMethods []string
+	// AllowedHeaders is the list of request headers allowed in preflight requests.
+	// If empty, the headers requested by the preflight request are allowed.
+	AllowedHeaders []string
+	// AllowCredentials indicates whether the request can include credentials such as cookies.
+	// When enabled, only origins that are explicitly listed in AllowedOrigins are allowed, since allowing any origin to make credentialed requests would let any site act on behalf of the user.
+	AllowCredentials bool
+	// MaxAge indicates how long the results of a preflight request can be cached by the client.
+	// If it's 0, the header isn't sent.
+	MaxAge time.Duration
+}
+
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// allowOrigin returns the value that should be used for the Access-Control-Allow-Origin header, or an empty string if the origin isn't allowed.
+func (opts CORSOptions) allowOrigin(origin string) (allowed string) {
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" {
+			if opts.AllowCredentials {
+				continue
+			}
+			return "*"
+		}
+		if o == origin {
+			return origin
+		}
+	}
+	return ""
+}
+
+// CORS returns middleware that adds Cross-Origin Resource Sharing headers to responses for allowed origins.
+// Preflight requests are responded to with a 204, without calling the next handler.
+// Since HandlerGroup middleware only runs for registered routes, preflight requests will only be handled for paths with an OPTIONS route registered.
+// To handle preflight requests for every path, register this using Server.GlobalMiddleware instead.
+func CORS(opts CORSOptions) Middleware {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(opts.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge / time.Second))
+
+	return func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			reqHeader := c.Request.Header()
+			origin := reqHeader.Get("Origin")
+			if origin == "" {
+				return next(c)
+			}
+			header := c.Response.Header()
+			header.Add("Vary", "Origin")
+			allowedOrigin := opts.allowOrigin(origin)
+			if allowedOrigin == "" {
+				return next(c)
+			}
+
+			header.Set("Access-Control-Allow-Origin", allowedOrigin)
+			if opts.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			isPreflight := c.Request.Method() == http.MethodOptions && reqHeader.Get("Access-Control-Request-Method") != ""
+			if !isPreflight {
+				return next(c)
+			}
+
+			header.Set("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", allowHeaders)
+			} else if requested := reqHeader.Get("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+			if opts.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", maxAge)
+			}
+			return c.Response.Status(http.StatusNoContent)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package lightwork
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrValidation is matched by errors returned from BodyStructValidated when the body was deserialised successfully, but failed validation.
+var ErrValidation = errors.New("validation failed")
+
+// ErrUnsupportedMediaType is matched by errors returned when the request's Content-Type isn't one that can be decoded.
+// If a handler returns such an error without writing a response, a 415 Unsupported Media Type is returned.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// unsupportedMediaTypeError describes a request Content-Type that didn't match the expected media type, and matches ErrUnsupportedMediaType.
+type unsupportedMediaTypeError struct {
+	contentType string
+	expected    string
+}
+
+func (umte unsupportedMediaTypeError) Error() string {
+	return fmt.Sprintf("unsupported media type %q, expected %s", umte.contentType, umte.expected)
+}
+
+func (umte unsupportedMediaTypeError) Is(target error) bool {
+	return target == ErrUnsupportedMediaType
+}
+
+// validationError wraps an error returned by the ValidateStruct function, so that it matches ErrValidation.
+type validationError struct {
+	err error
+}
+
+func (ve validationError) Error() string {
+	return "validation failed: " + ve.err.Error()
+}
+
+func (ve validationError) Unwrap() error {
+	return ve.err
+}
+
+func (ve validationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// HTTPError is an error that carries the HTTP status code and message that should be returned to the client.
+// If a handler returns an HTTPError without writing a response, the response will be written using the status code and message.
+type HTTPError struct {
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+// NewHTTPError returns an HTTPError with the provided status code and message.
+func NewHTTPError(statusCode int, message string) (he *HTTPError) {
+	return &HTTPError{
+		StatusCode: statusCode,
+		Message:    message,
+	}
+}
+
+// NewHTTPErrorf returns an HTTPError with the provided status code, and a message formatted using fmt.Errorf.
+// If the format includes a %w verb, the wrapped error will be available via errors.Unwrap.
+func NewHTTPErrorf(statusCode int, format string, values ...interface{}) (he *HTTPError) {
+	err := fmt.Errorf(format, values...)
+	return &HTTPError{
+		StatusCode: statusCode,
+		Message:    err.Error(),
+		Err:        errors.Unwrap(err),
+	}
+}
+
+// Error returns the status code and message.
+// If the Message is empty and an error is wrapped, the wrapped error is included, since the message won't already describe it.
+func (he *HTTPError) Error() string {
+	if he.Message == "" && he.Err != nil {
+		return fmt.Sprintf("%d %s: %v", he.StatusCode, he.message(), he.Err)
+	}
+	return fmt.Sprintf("%d %s", he.StatusCode, he.message())
+}
+
+func (he *HTTPError) Unwrap() error {
+	return he.Err
+}
+
+// message returns the message of the HTTPError, falling back to the standard status text if it's empty.
+func (he *HTTPError) message() (msg string) {
+	if he.Message == "" {
+		return http.StatusText(he.StatusCode)
+	}
+	return he.Message
+}
+
+// handleError logs an error returned from a handler, then gives the ErrorHandler and HTTPError a chance to write a response.
+// If nothing has been written and the error is context.DeadlineExceeded, a 504 Gateway Timeout is returned, or if it's ErrUnsupportedMediaType, a 415 Unsupported Media Type.
+func (s *Server) handleError(c *Context, err error) {
+	var he *HTTPError
+	isHTTPError := errors.As(err, &he)
+	if !isHTTPError && errors.Is(err, context.DeadlineExceeded) {
+		he, isHTTPError = NewHTTPError(http.StatusGatewayTimeout, ""), true
+	}
+	if !isHTTPError && errors.Is(err, ErrUnsupportedMediaType) {
+		he, isHTTPError = NewHTTPError(http.StatusUnsupportedMediaType, err.Error()), true
+	}
+	if isHTTPError && he.StatusCode < 500 {
+		c.Log.Infof("HTTP error returned from request handler: %v", err)
+	} else {
+		c.Log.Errorf("Error returned from request handler: %v", err)
+	}
+
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(c, err)
+	}
+	if c.Response.GetStatusCode() != 0 || c.Response.Size() != 0 {
+		return
+	}
+
+	if isHTTPError {
+		writeErr := c.Response.String(he.StatusCode, he.message())
+		if writeErr != nil {
+			c.Log.Errorf("Failed to write HTTP error response: %v", writeErr)
+		}
+		return
+	}
+	if s.ErrorHandler != nil {
+		c.Log.Warning("Error handler didn't write a response")
+	}
+}
@@ -1,7 +1,6 @@
 package lightwork
 
 import (
-	"context"
 	"net/http"
 
 	"github.com/julienschmidt/httprouter"
@@ -16,39 +15,15 @@ type HandlerGroup struct {
 	basePath       string
 }
 
-func (hg *HandlerGroup) handlerShim(h Handler) httprouter.Handle {
-	h = hg.middlewareHandler(h)
-	return func(rw http.ResponseWriter, req *http.Request, p httprouter.Params) {
-		c := &Context{
-			server:  hg.s,
-			Context: SimpleCtx{Context: context.Background()},
-		}
-		c.Response = ContextResponse{c: c, rw: &loggingResponseWriter{rw: rw}}
-		c.Request = ContextRequest{c: c, req: req, params: p}
-		rlb := hg.s.NewRequestLogger(c)
-		c.Log = &RequestLogger{
-			b: rlb,
-		}
-
-		err := h(c)
-		if err != nil {
-			c.Log.Errorf("Error returned from request handler: %v", err)
-		}
-		if c.Response.GetStatusCode() == 0 {
-			if c.Response.Size() == 0 {
-				c.Log.WTF("Handler didn't write a response")
-				c.Response.Status(500)
-			} else {
-				c.Response.rw.statusCode = 200
-			}
-		}
-		c.Log.b.WriteLogs()
-	}
+func (hg *HandlerGroup) handlerShim(route string, h Handler, routeMiddleware ...Middleware) httprouter.Handle {
+	return hg.s.routerHandle(route, hg.middlewareHandler(h, routeMiddleware...))
 }
 
-func (hg *HandlerGroup) middlewareHandler(userHandler Handler) (fullHandler Handler) {
-	fullHandler = userHandler
-	ml := hg.middlewareList
+// middlewareHandler wraps the handler with the provided route middleware, then the group's middleware, so that the group's middleware runs first.
+// Any response buffering started by the handler itself is ended as soon as it returns, before the middleware continues.
+func (hg *HandlerGroup) middlewareHandler(userHandler Handler, routeMiddleware ...Middleware) (fullHandler Handler) {
+	fullHandler = bufferScope(userHandler)
+	ml := append(append([]Middleware{}, hg.middlewareList...), routeMiddleware...)
 	for i := len(ml) - 1; i >= 0; i-- {
 		fullHandler = ml[i](fullHandler)
 	}
@@ -72,50 +47,112 @@ func (ohg *HandlerGroup) AddHandlerGroup(basePath string, registerFunc func(hg *
 	registerFunc(ohg.GetHandlerGroup(basePath))
 }
 
+// SetOPTIONSHandler registers the handler used for automatic OPTIONS responses for paths under the group's base path, which don't have an OPTIONS route registered.
+// The group's middleware is applied to the handler. See Server.SetOPTIONSHandler for details.
+func (hg *HandlerGroup) SetOPTIONSHandler(h Handler) {
+	hg.s.setOPTIONSHandler(hg.basePath, hg.middlewareHandler(h))
+}
+
 // AddMiddleware registers one or more middleware handlers.
 // Middleware is called in the order that it gets registered, and will only be applied to handlers that are added after it.
 func (hg *HandlerGroup) AddMiddleware(m ...Middleware) {
 	hg.middlewareList = append(hg.middlewareList, m...)
 }
 
-// DELETE registers a handler using the DELETE HTTP Method
-func (hg *HandlerGroup) DELETE(path string, h Handler) {
+// Handle registers a handler using the provided HTTP Method, which can be any method, including non-standard ones such as PROPFIND.
+// Any provided middleware is applied to this route only, after the group's middleware.
+// If the method is GET and the server's AutoHEAD is enabled, the handler is also registered using the HEAD HTTP Method, with the response body discarded.
+func (hg *HandlerGroup) Handle(method, path string, h Handler, m ...Middleware) {
 	path = hg.basePath + path
-	hg.s.router.DELETE(path, hg.handlerShim(h))
+	hg.register(method, path, hg.handlerShim(path, h, m...))
+	if method == http.MethodGet && hg.s.AutoHEAD {
+		// net/http discards the body of HEAD responses, while still using it to set Content-Length, so the handler can run as is.
+		hg.register(http.MethodHead, path, hg.handlerShim(path, h, m...))
+	}
+}
+
+// register registers the handle with the router using the full path, and records the route so that it's included in Server.Routes.
+// The router isn't safe for concurrent registration, so this is done while holding the server's routes mutex.
+func (hg *HandlerGroup) register(method, fullPath string, handle httprouter.Handle) {
+	hg.s.routesMutex.Lock()
+	defer hg.s.routesMutex.Unlock()
+	hg.s.router.Handle(method, fullPath, handle)
+	hg.s.routes = append(hg.s.routes, RouteInfo{
+		Method:    method,
+		Path:      fullPath,
+		GroupPath: hg.basePath,
+	})
+}
+
+// anyMethods are the methods registered by Any.
+var anyMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// Any registers a handler using all the common HTTP Methods: GET, HEAD, POST, PUT, PATCH, DELETE, and OPTIONS.
+// Any provided middleware is applied to this route only, after the group's middleware.
+func (hg *HandlerGroup) Any(path string, h Handler, m ...Middleware) {
+	for _, method := range anyMethods {
+		if method == http.MethodHead && hg.s.AutoHEAD {
+			continue
+		}
+		hg.Handle(method, path, h, m...)
+	}
+}
+
+// Methods registers a handler using each of the provided HTTP Methods, on the same path.
+// Any provided middleware is applied to this route only, after the group's middleware.
+func (hg *HandlerGroup) Methods(methods []string, path string, h Handler, m ...Middleware) {
+	for _, method := range methods {
+		hg.Handle(method, path, h, m...)
+	}
+}
+
+// DELETE registers a handler using the DELETE HTTP Method
+// Any provided middleware is applied to this route only, after the group's middleware.
+func (hg *HandlerGroup) DELETE(path string, h Handler, m ...Middleware) {
+	hg.Handle(http.MethodDelete, path, h, m...)
 }
 
 // GET registers a handler using the GET HTTP Method
-func (hg *HandlerGroup) GET(path string, h Handler) {
-	path = hg.basePath + path
-	hg.s.router.GET(path, hg.handlerShim(h))
+// Any provided middleware is applied to this route only, after the group's middleware.
+// If the server's AutoHEAD is enabled, the handler is also registered using the HEAD HTTP Method, with the response body discarded.
+func (hg *HandlerGroup) GET(path string, h Handler, m ...Middleware) {
+	hg.Handle(http.MethodGet, path, h, m...)
 }
 
 // HEAD registers a handler using the HEAD HTTP Method
-func (hg *HandlerGroup) HEAD(path string, h Handler) {
-	path = hg.basePath + path
-	hg.s.router.HEAD(path, hg.handlerShim(h))
+// Any provided middleware is applied to this route only, after the group's middleware.
+func (hg *HandlerGroup) HEAD(path string, h Handler, m ...Middleware) {
+	hg.Handle(http.MethodHead, path, h, m...)
 }
 
 // OPTIONS registers a handler using the OPTIONS HTTP Method
-func (hg *HandlerGroup) OPTIONS(path string, h Handler) {
-	path = hg.basePath + path
-	hg.s.router.OPTIONS(path, hg.handlerShim(h))
+// Any provided middleware is applied to this route only, after the group's middleware.
+func (hg *HandlerGroup) OPTIONS(path string, h Handler, m ...Middleware) {
+	hg.Handle(http.MethodOptions, path, h, m...)
 }
 
 // PATCH registers a handler using the PATCH HTTP Method
-func (hg *HandlerGroup) PATCH(path string, h Handler) {
-	path = hg.basePath + path
-	hg.s.router.PATCH(path, hg.handlerShim(h))
+// Any provided middleware is applied to this route only, after the group's middleware.
+func (hg *HandlerGroup) PATCH(path string, h Handler, m ...Middleware) {
+	hg.Handle(http.MethodPatch, path, h, m...)
 }
 
 // POST registers a handler using the POST HTTP Method
-func (hg *HandlerGroup) POST(path string, h Handler) {
-	path = hg.basePath + path
-	hg.s.router.POST(path, hg.handlerShim(h))
+// Any provided middleware is applied to this route only, after the group's middleware.
+func (hg *HandlerGroup) POST(path string, h Handler, m ...Middleware) {
+	hg.Handle(http.MethodPost, path, h, m...)
 }
 
 // PUT registers a handler using the PUT HTTP Method
-func (hg *HandlerGroup) PUT(path string, h Handler) {
-	path = hg.basePath + path
-	hg.s.router.PUT(path, hg.handlerShim(h))
+// Any provided middleware is applied to this route only, after the group's middleware.
+func (hg *HandlerGroup) PUT(path string, h Handler, m ...Middleware) {
+	hg.Handle(http.MethodPut, path, h, m...)
 }
@@ -0,0 +1,53 @@
+package lightwork
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerGroupMethods(t *testing.T) {
+	s := newTestServer()
+	var methods []string
+	s.GetHandlerGroup("").Methods([]string{http.MethodPost, http.MethodPut}, "/items", func(c *Context) (err error) {
+		methods = append(methods, c.Request.Method())
+		return c.Response.Status(http.StatusNoContent)
+	})
+
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		rec := serveTest(s, httptest.NewRequest(method, "/items", nil))
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("expected %s to reach the handler, got %d", method, rec.Code)
+		}
+	}
+	if len(methods) != 2 || methods[0] != http.MethodPost || methods[1] != http.MethodPut {
+		t.Fatalf("expected the handler to run for POST then PUT, got %v", methods)
+	}
+
+	rec := serveTest(s, httptest.NewRequest(http.MethodDelete, "/items", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected an unlisted method to return a 405, got %d", rec.Code)
+	}
+}
+
+func TestAutoHEADContentLength(t *testing.T) {
+	s := newTestServer()
+	s.AutoHEAD = true
+	s.GetHandlerGroup("").GET("/j", func(c *Context) (err error) {
+		return c.Response.String(http.StatusOK, "0123456789")
+	})
+	ts := s.StartTest()
+	defer ts.Close()
+
+	resp, err := http.Head(ts.URL + "/j")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected a 200, got %d", resp.StatusCode)
+	}
+	if cl := resp.Header.Get("Content-Length"); cl != "10" {
+		t.Fatalf("expected a Content-Length of 10, got %q", cl)
+	}
+}
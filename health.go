@@ -0,0 +1,177 @@
+package lightwork
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HealthCheck checks whether a dependency of the server is available, returning an error if it isn't.
+// It should return promptly once the provided context is done.
+type HealthCheck func(ctx context.Context) (err error)
+
+// HealthOptions configures the health check routes registered by Server.AddHealthChecks.
+type HealthOptions struct {
+	// LivenessPath is the path of the liveness route, which always responds with a 200 while the server is running. If empty, "/healthz" is used.
+	LivenessPath string
+	// ReadinessPath is the path of the readiness route, which runs the readiness checks. If empty, "/readyz" is used.
+	ReadinessPath string
+	// Timeout bounds how long the readiness checks are given to complete. Checks that haven't completed in time are reported as failed. If it's 0, 5 seconds is used.
+	Timeout time.Duration
+	// Checks are the named readiness checks. More can be added later using HealthChecks.Add.
+	Checks map[string]HealthCheck
+	// Middleware is applied to both routes.
+	Middleware []Middleware
+}
+
+// HealthChecks is the set of readiness checks run by the readiness route registered by Server.AddHealthChecks.
+// Checks can be added at any time, including after the server has started.
+type HealthChecks struct {
+	mutex   sync.RWMutex
+	checks  map[string]HealthCheck
+	timeout time.Duration
+}
+
+// healthResponse is the JSON body returned by the health check routes.
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Add registers a named readiness check, replacing any existing check with the same name.
+func (hc *HealthChecks) Add(name string, check HealthCheck) {
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	hc.checks[name] = check
+}
+
+// Remove unregisters the named readiness check, if it exists.
+func (hc *HealthChecks) Remove(name string) {
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+	delete(hc.checks, name)
+}
+
+// runCheck runs the check, converting a panic into an error so that a faulty check can't crash the server.
+func runCheck(ctx context.Context, check HealthCheck) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("check panicked: %v", r)
+		}
+	}()
+	return check(ctx)
+}
+
+// run runs all of the checks concurrently, returning the error of each failed check by name.
+// Checks that haven't completed before the timeout are reported as failed, without waiting for them to return.
+func (hc *HealthChecks) run(ctx context.Context) (results map[string]error) {
+	hc.mutex.RLock()
+	checks := make(map[string]HealthCheck, len(hc.checks))
+	for name, check := range hc.checks {
+		checks[name] = check
+	}
+	hc.mutex.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+	// The channel is buffered so that checks which complete after the timeout don't block forever.
+	resultChan := make(chan result, len(checks))
+	for name, check := range checks {
+		go func(name string, check HealthCheck) {
+			resultChan <- result{name: name, err: runCheck(ctx, check)}
+		}(name, check)
+	}
+
+	results = make(map[string]error, len(checks))
+	for len(results) < len(checks) {
+		select {
+		case r := <-resultChan:
+			results[r.name] = r.err
+		case <-ctx.Done():
+			for name := range checks {
+				if _, ok := results[name]; !ok {
+					results[name] = fmt.Errorf("check didn't complete in time: %w", ctx.Err())
+				}
+			}
+		}
+	}
+	return
+}
+
+// writeHealthResponse writes the health response as JSON, independently of the server's configured EncodeStruct function.
+func writeHealthResponse(c *Context, statusCode int, resp healthResponse) (err error) {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to encode health response: %w", err)
+	}
+	c.Response.setHeaderIfNotAlreadySet("Content-Type", "application/json")
+	c.Response.setHeaderIfNotAlreadySet("Cache-Control", "no-store")
+	return c.Response.Bytes(statusCode, body)
+}
+
+// readinessHandler runs the readiness checks, responding with a 200 if they all pass, or a 503 if any fail.
+func (hc *HealthChecks) readinessHandler(c *Context) (err error) {
+	results := hc.run(c.Context)
+	resp := healthResponse{Status: "ok", Checks: make(map[string]string, len(results))}
+	statusCode := http.StatusOK
+	failed := make([]string, 0)
+	for name, checkErr := range results {
+		if checkErr == nil {
+			resp.Checks[name] = "ok"
+			continue
+		}
+		resp.Checks[name] = checkErr.Error()
+		failed = append(failed, name)
+	}
+	if len(failed) != 0 {
+		sort.Strings(failed)
+		resp.Status = "unavailable"
+		statusCode = http.StatusServiceUnavailable
+		c.Log.Warningf("Readiness checks failed: %v", failed)
+	}
+	return writeHealthResponse(c, statusCode, resp)
+}
+
+// livenessHandler responds with a 200, indicating that the server is able to serve requests.
+func livenessHandler(c *Context) (err error) {
+	return writeHealthResponse(c, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// AddHealthChecks registers GET routes for liveness and readiness probes, such as those used by Kubernetes, and returns the readiness checks so that more can be added later.
+// The readiness route runs all of the checks concurrently, responding with a 200 and a JSON summary if they all pass, or a 503 listing the failures otherwise.
+// The routes are registered in their own HandlerGroup, so middleware registered on other groups, such as authentication, doesn't apply to them. Global middleware still applies.
+// They're logged like any other route. To exclude them from the logs, the server's LogFilter can compare c.Request.RoutePattern() against the paths.
+func (s *Server) AddHealthChecks(opts HealthOptions) (hc *HealthChecks) {
+	if opts.LivenessPath == "" {
+		opts.LivenessPath = "/healthz"
+	}
+	if opts.ReadinessPath == "" {
+		opts.ReadinessPath = "/readyz"
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	hc = &HealthChecks{
+		checks:  make(map[string]HealthCheck, len(opts.Checks)),
+		timeout: opts.Timeout,
+	}
+	for name, check := range opts.Checks {
+		hc.checks[name] = check
+	}
+
+	hg := s.GetHandlerGroup("")
+	hg.AddMiddleware(opts.Middleware...)
+	hg.GET(opts.LivenessPath, livenessHandler)
+	hg.GET(opts.ReadinessPath, hc.readinessHandler)
+	return
+}
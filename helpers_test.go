@@ -0,0 +1,47 @@
+package lightwork
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+)
+
+// nopLogger is a RequestLoggerBase that discards all logs.
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string)    {}
+func (nopLogger) Warning(msg string) {}
+func (nopLogger) Error(msg string)   {}
+func (nopLogger) WTF(msg string)     {}
+func (nopLogger) WriteLogs()         {}
+
+func (nopLogger) FormatLog(format string, values ...interface{}) (msg string) {
+	return fmt.Sprintf(format, values...)
+}
+
+// newTestServer returns a JSON server that discards its logs.
+func newTestServer() (s *Server) {
+	s = NewJSONServer()
+	s.AccessLogger = nil
+	s.NewRequestLogger = func(c *Context) RequestLoggerBase { return nopLogger{} }
+	return
+}
+
+// headerCountingRecorder is a ResponseRecorder that counts how many times WriteHeader is called.
+type headerCountingRecorder struct {
+	*httptest.ResponseRecorder
+	writeHeaderCalls int
+}
+
+func (rec *headerCountingRecorder) WriteHeader(statusCode int) {
+	rec.writeHeaderCalls++
+	rec.ResponseRecorder.WriteHeader(statusCode)
+}
+
+// serveTest serves the request using the server, returning the recorded response.
+func serveTest(s *Server, req *http.Request) (rec *headerCountingRecorder) {
+	s.prepareRouter()
+	rec = &headerCountingRecorder{ResponseRecorder: httptest.NewRecorder()}
+	s.ServeHTTP(rec, req)
+	return
+}
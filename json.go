@@ -0,0 +1,103 @@
+package lightwork
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DecodeError describes why a JSON request body couldn't be deserialised, so that a helpful message can be returned to the client.
+// It's returned by JSONDecoder and JSONDecoderStrict for malformed JSON, and for values of the wrong type.
+type DecodeError struct {
+	// Offset is the byte offset in the body at which the error was detected.
+	Offset int64
+	// Field is the dot-separated path of the field that had the wrong type, or empty for malformed JSON, or values at the top level.
+	Field string
+	// Expected is the Go type that the value should have been deserialised into, or empty for malformed JSON.
+	Expected string
+	// Value describes the JSON value that was provided, such as "string" or "number", or empty for malformed JSON.
+	Value string
+	// Err is the underlying error returned by encoding/json.
+	Err error
+}
+
+func (de *DecodeError) Error() string {
+	if de.Expected == "" {
+		return fmt.Sprintf("invalid JSON at offset %d: %v", de.Offset, de.Err)
+	}
+	if de.Field == "" {
+		return fmt.Sprintf("invalid value at offset %d: expected %s, got %s", de.Offset, de.Expected, de.Value)
+	}
+	return fmt.Sprintf("invalid value for field %q at offset %d: expected %s, got %s", de.Field, de.Offset, de.Expected, de.Value)
+}
+
+func (de *DecodeError) Unwrap() error {
+	return de.Err
+}
+
+// decodeJSONError converts syntax and type errors returned by encoding/json into a DecodeError, returning other errors as they are.
+func decodeJSONError(err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &DecodeError{
+			Offset: syntaxErr.Offset,
+			Err:    err,
+		}
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &DecodeError{
+			Offset:   typeErr.Offset,
+			Field:    typeErr.Field,
+			Expected: typeErr.Type.String(),
+			Value:    typeErr.Value,
+			Err:      err,
+		}
+	}
+	return err
+}
+
+// JSONEncoder serialises the input as JSON, streaming it directly to the output.
+// It matches the signature of Server.EncodeStruct.
+func JSONEncoder(c *Context, input interface{}, output io.Writer) (err error) {
+	return json.NewEncoder(output).Encode(input)
+}
+
+// JSONDecoder deserialises JSON from the input into the result, which must be a pointer.
+// Malformed JSON and values of the wrong type are returned as a *DecodeError.
+// It matches the signature of Server.DecodeStruct.
+func JSONDecoder(c *Context, input io.Reader, result interface{}) (err error) {
+	return decodeJSONError(json.NewDecoder(input).Decode(result))
+}
+
+// JSONDecoderStrict is the same as JSONDecoder, except that it returns an error if the input contains fields that don't exist in the result.
+func JSONDecoderStrict(c *Context, input io.Reader, result interface{}) (err error) {
+	decoder := json.NewDecoder(input)
+	decoder.DisallowUnknownFields()
+	return decodeJSONError(decoder.Decode(result))
+}
+
+// jsonContentTypeHook sets the Content-Type of struct responses to application/json, unless it has already been set.
+func jsonContentTypeHook(c *Context) {
+	c.Response.setHeaderIfNotAlreadySet("Content-Type", "application/json")
+}
+
+// UseJSON configures the server to use JSONEncoder and JSONDecoder for structs, and to set the Content-Type of struct responses to application/json.
+// If strict is true, JSONDecoderStrict is used instead of JSONDecoder.
+func (s *Server) UseJSON(strict bool) {
+	s.EncodeStructPreHook = jsonContentTypeHook
+	s.EncodeStruct = JSONEncoder
+	if strict {
+		s.DecodeStruct = JSONDecoderStrict
+	} else {
+		s.DecodeStruct = JSONDecoder
+	}
+}
+
+// NewJSONServer returns a new Server configured to use JSON for structs, as per UseJSON.
+func NewJSONServer() (server *Server) {
+	server = NewServer()
+	server.UseJSON(false)
+	return
+}
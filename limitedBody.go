@@ -0,0 +1,67 @@
+package lightwork
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// ErrBodyTooLarge is returned when reading a request body that exceeds the configured limit.
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// limitedBody wraps a request body limited using http.MaxBytesReader, returning ErrBodyTooLarge once more than the remaining number of bytes are read.
+type limitedBody struct {
+	rc        io.ReadCloser
+	remaining int64
+}
+
+// newLimitedBody limits the request body to max bytes. Exceeding the limit also tells the server to close the connection once the response has been written.
+func newLimitedBody(rw http.ResponseWriter, body io.ReadCloser, max int64) (lb *limitedBody) {
+	return &limitedBody{rc: http.MaxBytesReader(rw, body, max), remaining: max}
+}
+
+func (lb *limitedBody) Read(p []byte) (n int, err error) {
+	n, err = lb.rc.Read(p)
+	lb.remaining -= int64(n)
+	if err != nil && err != io.EOF && lb.remaining <= 0 {
+		err = ErrBodyTooLarge
+	}
+	return
+}
+
+func (lb *limitedBody) Close() (err error) {
+	return lb.rc.Close()
+}
+
+// replayedBody reads bytes that were already read from a request body, followed by the rest of the body, so that the body can be read again in full.
+type replayedBody struct {
+	r  io.Reader
+	rc io.ReadCloser
+}
+
+func (rb *replayedBody) Read(p []byte) (n int, err error) {
+	return rb.r.Read(p)
+}
+
+func (rb *replayedBody) Close() (err error) {
+	return rb.rc.Close()
+}
+
+// cachedBody reads a request body that has been cached in memory, returning the error encountered while caching it, if any, once the cached bytes have been read.
+type cachedBody struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (cb *cachedBody) Read(p []byte) (n int, err error) {
+	n, err = cb.r.Read(p)
+	if err == io.EOF && cb.err != nil {
+		err = cb.err
+	}
+	return
+}
+
+func (cb *cachedBody) Close() (err error) {
+	return nil
+}
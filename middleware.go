@@ -16,16 +16,26 @@ func ClassicHandlerShim(classicHandler http.Handler) Handler {
 
 // ClassicMiddlewareShim is a helper function that allows you to use a classic Go middleware handler as middleware.
 // If your middleware accepts more than one parameter, you'll have to curry the other parameters, as this only allows for simple middleware with a single http.Handler parameter.
+// The classic middleware is given the real ResponseWriter and Request, so any response it writes is sent to the client.
+// If it doesn't call the next handler, such as when rejecting a request, the next handler isn't run. Otherwise, the next handler is run at most once, with the Request the middleware passed to it.
+// The next handler writes directly to the response, so classic middleware that wraps the ResponseWriter won't observe those writes.
 func ClassicMiddlewareShim(classicMiddleware func(next http.Handler) http.Handler) Middleware {
 	return func(n Handler) Handler {
 		return func(c *Context) (err error) {
+			called := false
 			classicHandler := classicMiddleware(
-				http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
-					n(c)
+				http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+					if called {
+						return
+					}
+					called = true
+					c.Request.req = req
+					err = n(c)
 				}),
 			)
 
-			return ClassicHandlerShim(classicHandler)(c)
+			classicHandler.ServeHTTP(c.Response.rw, c.Request.req)
+			return
 		}
 	}
 }
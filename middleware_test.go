@@ -0,0 +1,57 @@
+package lightwork
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClassicMiddlewareShimShortCircuit(t *testing.T) {
+	s := newTestServer()
+	handlerCalls := 0
+	hg := s.GetHandlerGroup("")
+	hg.AddMiddleware(ClassicMiddlewareShim(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusUnauthorized)
+		})
+	}))
+	hg.GET("/", func(c *Context) (err error) {
+		handlerCalls++
+		return c.Response.Status(http.StatusOK)
+	})
+
+	rec := serveTest(s, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the middleware's 401, got %d", rec.Code)
+	}
+	if handlerCalls != 0 {
+		t.Fatalf("expected the handler not to run, but it ran %d times", handlerCalls)
+	}
+}
+
+func TestClassicMiddlewareShimCallsNext(t *testing.T) {
+	s := newTestServer()
+	handlerCalls := 0
+	hg := s.GetHandlerGroup("")
+	hg.AddMiddleware(ClassicMiddlewareShim(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("X-Classic", "true")
+			next.ServeHTTP(rw, req)
+		})
+	}))
+	hg.GET("/", func(c *Context) (err error) {
+		handlerCalls++
+		return c.Response.Status(http.StatusOK)
+	})
+
+	rec := serveTest(s, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the handler's 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-Classic") != "true" {
+		t.Fatal("expected the header set by the middleware to be present")
+	}
+	if handlerCalls != 1 {
+		t.Fatalf("expected the handler to run once, but it ran %d times", handlerCalls)
+	}
+}
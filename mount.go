@@ -0,0 +1,22 @@
+package lightwork
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Mount serves the provided http.Handler for every request under the provided path prefix, such as an httputil.ReverseProxy.
+// The handler is registered for all the methods registered by Any, and runs the group's middleware as normal.
+// The prefix is stripped from the request's URL path before it's passed to the handler, and the request's context is replaced with the Context's context.
+func (hg *HandlerGroup) Mount(prefix string, handler http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	hg.Any(prefix+"/*filepath", func(c *Context) (err error) {
+		req := c.Request.req.WithContext(c.Context.Context)
+		u := *req.URL
+		u.Path = c.Request.GetParam("filepath")
+		u.RawPath = ""
+		req.URL = &u
+		handler.ServeHTTP(c.Response.rw, req)
+		return nil
+	})
+}
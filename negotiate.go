@@ -0,0 +1,112 @@
+package lightwork
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a single entry from an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept parses the value of an Accept header into its media ranges.
+// Malformed entries are skipped.
+func parseAccept(header string) (ranges []mediaRange) {
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		slash := strings.Index(mediaType, "/")
+		if slash < 0 {
+			continue
+		}
+		mr := mediaRange{typ: mediaType[:slash], subtype: mediaType[slash+1:], q: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil {
+					mr.q = q
+				}
+			}
+		}
+		ranges = append(ranges, mr)
+	}
+	return
+}
+
+// quality returns the quality value of the most specific media range matching the provided media type, or 0 if none match.
+func quality(ranges []mediaRange, mediaType string) (q float64) {
+	mediaType = strings.ToLower(mediaType)
+	if semi := strings.Index(mediaType, ";"); semi >= 0 {
+		mediaType = strings.TrimSpace(mediaType[:semi])
+	}
+	slash := strings.Index(mediaType, "/")
+	if slash < 0 {
+		return 0
+	}
+	typ, subtype := mediaType[:slash], mediaType[slash+1:]
+
+	bestSpecificity := -1
+	for _, mr := range ranges {
+		specificity := -1
+		switch {
+		case mr.typ == typ && mr.subtype == subtype:
+			specificity = 2
+		case mr.typ == typ && mr.subtype == "*":
+			specificity = 1
+		case mr.typ == "*" && mr.subtype == "*":
+			specificity = 0
+		}
+		if specificity > bestSpecificity {
+			bestSpecificity = specificity
+			q = mr.q
+		}
+	}
+	return
+}
+
+// Accepts returns the offered media type that best matches the request's Accept header, honouring quality values and wildcards.
+// If multiple offers match equally well, the earliest one is returned.
+// If the request has no Accept header, the first offer is returned. If no offers are acceptable, an empty string is returned.
+func (cr ContextRequest) Accepts(offers ...string) (best string) {
+	header := cr.Header().Get("Accept")
+	if header == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	ranges := parseAccept(header)
+	bestQ := 0.0
+	for _, offer := range offers {
+		q := quality(ranges, offer)
+		if q > bestQ {
+			best = offer
+			bestQ = q
+		}
+	}
+	return
+}
+
+// Negotiate returns the provided status code, and the data serialised as either JSON or XML, depending on the request's Accept header.
+// JSON is serialised using Struct, so the server's EncodeStruct function must produce JSON. XML is serialised using XML.
+// If neither is acceptable, a 406 is returned with no body.
+func (cr ContextResponse) Negotiate(statusCode int, data interface{}) (err error) {
+	switch cr.c.Request.Accepts("application/json", "application/xml", "text/xml") {
+	case "application/json":
+		cr.setHeaderIfNotAlreadySet("Content-Type", "application/json")
+		return cr.Struct(statusCode, data)
+	case "application/xml":
+		return cr.XML(statusCode, data)
+	case "text/xml":
+		cr.setHeaderIfNotAlreadySet("Content-Type", "text/xml")
+		return cr.XML(statusCode, data)
+	default:
+		return cr.Status(http.StatusNotAcceptable)
+	}
+}
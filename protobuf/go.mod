@@ -0,0 +1,10 @@
+module github.com/rsheasby/lightwork/protobuf
+
+go 1.18
+
+require (
+	github.com/rsheasby/lightwork v0.0.0-20261014051113-e62650e0fe1b
+	google.golang.org/protobuf v1.33.0
+)
+
+require github.com/julienschmidt/httprouter v1.3.0 // indirect
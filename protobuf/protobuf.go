@@ -0,0 +1,61 @@
+// Package protobuf provides struct encoding and decoding functions for lightwork servers that use Protocol Buffers.
+// It's a separate module, so that the core module doesn't depend on the protobuf module.
+// It requires a published version of the core module. To develop both together, use a workspace, such as one created by running "go work init . ./protobuf" in the repository root.
+package protobuf
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/rsheasby/lightwork"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentType is the Content-Type used for protobuf responses.
+const ContentType = "application/x-protobuf"
+
+// Encoder serialises the input, which must be a proto.Message, in the protobuf wire format.
+// The Content-Type of the response is set to application/x-protobuf, unless it has already been set.
+// It matches the signature of Server.EncodeStruct.
+func Encoder(c *lightwork.Context, input interface{}, output io.Writer) (err error) {
+	msg, ok := input.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot encode %T as protobuf, since it doesn't implement proto.Message", input)
+	}
+	body, err := proto.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode protobuf: %w", err)
+	}
+	header := c.Response.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", ContentType)
+	}
+	_, err = output.Write(body)
+	return
+}
+
+// Decoder deserialises the protobuf wire format from the input into the result, which must be a proto.Message.
+// It matches the signature of Server.DecodeStruct.
+func Decoder(c *lightwork.Context, input io.Reader, result interface{}) (err error) {
+	msg, ok := result.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot decode protobuf into %T, since it doesn't implement proto.Message", result)
+	}
+	body, err := io.ReadAll(input)
+	if err != nil {
+		return fmt.Errorf("failed to read protobuf: %w", err)
+	}
+	err = proto.Unmarshal(body, msg)
+	if err != nil {
+		return fmt.Errorf("failed to decode protobuf: %w", err)
+	}
+	return nil
+}
+
+// Use configures the server to use Encoder and Decoder for structs, replacing any previously configured EncodeStructPreHook, such as the one set by UseJSON.
+// Messages can then be returned using c.Response.Struct, and read using c.Request.BodyStruct.
+func Use(s *lightwork.Server) {
+	s.EncodeStructPreHook = nil
+	s.EncodeStruct = Encoder
+	s.DecodeStruct = Decoder
+}
@@ -0,0 +1,147 @@
+package lightwork
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitStore stores the token buckets used by the RateLimit middleware.
+// Implementations must be safe for concurrent use.
+type RateLimitStore interface {
+	// Take attempts to take a token from the bucket for the key, which refills at the provided rate per second, up to the burst size.
+	// If no token is available, it returns false, along with how long until a token will be available.
+	Take(key string, rate float64, burst int) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// RateLimitOptions configures the RateLimit middleware.
+type RateLimitOptions struct {
+	// Rate is the number of requests allowed per second, on average.
+	Rate float64
+	// Burst is the maximum number of requests allowed at once. If it's less than 1, it defaults to 1.
+	Burst int
+	// KeyFunc returns the key that requests are limited by. If it's nil, requests are limited by ContextRequest.ClientIP.
+	// If the key is empty, such as when the client IP can't be determined, the request isn't limited, rather than sharing a single bucket with every other such request.
+	KeyFunc func(c *Context) string
+	// Store stores the token buckets. If it's nil, a new MemoryRateLimitStore is used.
+	Store RateLimitStore
+}
+
+// tokenBucket is the state of a single key in a MemoryRateLimitStore.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will have refilled completely, based on the rate and burst size it was last taken from with.
+	full time.Time
+}
+
+// MemoryRateLimitStore is an in-memory RateLimitStore.
+// Buckets that have been idle long enough to refill completely are evicted periodically, since they're equivalent to new buckets.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// memoryRateLimitSweepInterval is the minimum interval between sweeps for idle buckets.
+const memoryRateLimitSweepInterval = time.Minute
+
+// NewMemoryRateLimitStore returns an empty MemoryRateLimitStore.
+func NewMemoryRateLimitStore() (store *MemoryRateLimitStore) {
+	return &MemoryRateLimitStore{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take implements RateLimitStore.
+func (s *MemoryRateLimitStore) Take(key string, rate float64, burst int) (allowed bool, retryAfter time.Duration, err error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= memoryRateLimitSweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	allowed = b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	if allowed {
+		return true, 0, nil
+	}
+	wait := (1 - b.tokens) / rate
+	return false, time.Duration(wait * float64(time.Second)), nil
+}
+
+// sweep evicts buckets that would have refilled completely by now.
+// Each bucket records when it will be full, so that buckets used with different rates and burst sizes are only evicted once they're equivalent to new buckets.
+// The mutex must be held when calling this.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// RateLimit returns middleware that limits the rate of requests using a token bucket per key.
+// When the limit is exceeded, a 429 is returned with a Retry-After header, without calling the next handler.
+// If the store returns an error, it's logged and the request is allowed, so that an unavailable store doesn't cause an outage.
+func RateLimit(opts RateLimitOptions) Middleware {
+	if opts.Rate <= 0 {
+		panic("rate limit must be greater than 0")
+	}
+	if opts.Burst < 1 {
+		opts.Burst = 1
+	}
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = func(c *Context) string {
+			ip := c.Request.ClientIP()
+			if ip == nil {
+				return ""
+			}
+			return ip.String()
+		}
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryRateLimitStore()
+	}
+
+	return func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			key := opts.KeyFunc(c)
+			if key == "" {
+				c.Log.Warning("Unable to determine rate limit key, allowing request")
+				return next(c)
+			}
+			allowed, retryAfter, err := opts.Store.Take(key, opts.Rate, opts.Burst)
+			if err != nil {
+				c.Log.Warningf("Rate limit store failed, allowing request: %v", err)
+				return next(c)
+			}
+			if !allowed {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				c.Response.Header().Set("Retry-After", strconv.Itoa(seconds))
+				return c.Response.Status(http.StatusTooManyRequests)
+			}
+			return next(c)
+		}
+	}
+}
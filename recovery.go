@@ -0,0 +1,36 @@
+package lightwork
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Recovery returns middleware that recovers from panics in later middleware and handlers.
+// The panic is logged as a WTF, including a stack trace, and returned as an *HTTPError with a 500 status code, wrapping the recovered value.
+// Logging the recovered panic doesn't cause another panic, even in DevelopmentMode.
+// This means the ErrorHandler will still be called if configured, and a 500 will be written if nothing else writes a response.
+// Recovery should usually be the first middleware registered on the top-level HandlerGroup, so that it covers everything.
+func Recovery() Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				c.Log.logWTF(fmt.Sprintf("Recovered from panic: %v", r))
+				panicErr, ok := r.(error)
+				if ok {
+					panicErr = fmt.Errorf("recovered from panic: %w", panicErr)
+				} else {
+					panicErr = fmt.Errorf("recovered from panic: %v", r)
+				}
+				err = &HTTPError{
+					StatusCode: http.StatusInternalServerError,
+					Err:        panicErr,
+				}
+			}()
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package lightwork
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+)
+
+// Renderer renders named templates, for use with ContextResponse.Render.
+type Renderer interface {
+	Render(w io.Writer, name string, data interface{}) (err error)
+}
+
+// TemplateRenderer is a Renderer backed by html/template.
+type TemplateRenderer struct {
+	Templates *template.Template
+}
+
+// NewTemplateRendererGlob returns a TemplateRenderer using the templates matching the provided glob pattern, as per template.ParseGlob.
+func NewTemplateRendererGlob(pattern string) (tr *TemplateRenderer, err error) {
+	t, err := template.ParseGlob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+	return &TemplateRenderer{Templates: t}, nil
+}
+
+// NewTemplateRendererFS returns a TemplateRenderer using the templates within fsys matching the provided glob patterns, as per template.ParseFS.
+func NewTemplateRendererFS(fsys fs.FS, patterns ...string) (tr *TemplateRenderer, err error) {
+	t, err := template.ParseFS(fsys, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+	return &TemplateRenderer{Templates: t}, nil
+}
+
+// Render executes the named template with the provided data, writing the output to w.
+func (tr *TemplateRenderer) Render(w io.Writer, name string, data interface{}) (err error) {
+	return tr.Templates.ExecuteTemplate(w, name, data)
+}
+
+// Render returns the provided status code, and the named template rendered with the provided data, using the server's configured Renderer.
+// If the Content-Type header is not already set, it will be set to text/html; charset=utf-8.
+// As with Struct, the status code isn't written until the renderer first writes to the body, so a render error before then will still allow an error response to be written.
+// If rendering fails after the response has started, the error is logged, since the response can no longer be changed.
+func (cr ContextResponse) Render(statusCode int, name string, data interface{}) (err error) {
+	if cr.c.server.Renderer == nil {
+		return fmt.Errorf("no renderer configured")
+	}
+	sw := &structWriter{cr: cr, statusCode: statusCode, contentType: "text/html; charset=utf-8"}
+	err = cr.c.server.Renderer.Render(sw, name, data)
+	if err != nil {
+		if sw.wroteHeader {
+			cr.c.Log.Errorf("Failed to render template %q after the response had started: %v", name, err)
+		}
+		return fmt.Errorf("failed to render template %q: %w", name, err)
+	}
+	sw.writeHeader()
+	return
+}
@@ -0,0 +1,78 @@
+package lightwork
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// DefaultRequestIDHeader is the header used by the RequestID middleware, unless overridden with RequestIDHeader.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the key under which the RequestID middleware stores the request ID on the Context.
+var RequestIDKey = ContextKey[string]("request ID")
+
+type requestIDConfig struct {
+	header    string
+	generator func() string
+}
+
+// RequestIDOption configures the RequestID middleware.
+type RequestIDOption func(cfg *requestIDConfig)
+
+// RequestIDHeader sets the name of the header used to read and return the request ID.
+func RequestIDHeader(name string) RequestIDOption {
+	return func(cfg *requestIDConfig) {
+		cfg.header = name
+	}
+}
+
+// RequestIDGenerator sets the function used to generate a request ID, when the request doesn't include one.
+func RequestIDGenerator(generator func() string) RequestIDOption {
+	return func(cfg *requestIDConfig) {
+		cfg.generator = generator
+	}
+}
+
+// NewRequestID generates a random version 4 UUID, for use as a request ID.
+func NewRequestID() (id string) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// RequestID returns middleware that assigns an ID to every request.
+// The ID is read from the request header if present, otherwise a new one is generated.
+// The ID is stored on the Context under RequestIDKey, returned in the response header, and attached to all later logs as the "request_id" field.
+func RequestID(opts ...RequestIDOption) Middleware {
+	cfg := &requestIDConfig{
+		header:    DefaultRequestIDHeader,
+		generator: NewRequestID,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			id := c.Request.Header().Get(cfg.header)
+			if id == "" {
+				id = cfg.generator()
+			}
+			RequestIDKey.Set(c, id)
+			c.Response.Header().Set(cfg.header, id)
+			c.Log = c.Log.With("request_id", id)
+			return next(c)
+		}
+	}
+}
+
+// RequestID returns the ID assigned to the request by the RequestID middleware, or an empty string if there isn't one.
+func (cr ContextRequest) RequestID() (id string) {
+	id, _ = RequestIDKey.Get(cr.c)
+	return
+}
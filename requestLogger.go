@@ -3,6 +3,9 @@ package lightwork
 import (
 	"fmt"
 	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
 )
 
 // RequestLoggerBase provides the basic interface required to log at 4 simple levels.
@@ -21,14 +24,152 @@ type RequestLoggerBase interface {
 	WriteLogs()
 }
 
+// FieldRequestLoggerBase extends RequestLoggerBase with methods that accept structured key-value fields.
+// If the RequestLoggerBase returned by Server.NewRequestLogger implements this, fields added using RequestLogger.With are passed through separately.
+// Otherwise, the fields are appended to the message as key=value pairs.
+type FieldRequestLoggerBase interface {
+	RequestLoggerBase
+	// InfoFields is the same as Info, with the provided fields attached.
+	InfoFields(msg string, fields map[string]interface{})
+	// WarningFields is the same as Warning, with the provided fields attached.
+	WarningFields(msg string, fields map[string]interface{})
+	// ErrorFields is the same as Error, with the provided fields attached.
+	ErrorFields(msg string, fields map[string]interface{})
+	// WTFFields is the same as WTF, with the provided fields attached.
+	WTFFields(msg string, fields map[string]interface{})
+}
+
+type logLevel int
+
+const (
+	logLevelInfo logLevel = iota
+	logLevelWarning
+	logLevelError
+	logLevelWTF
+)
+
+// logToBase logs the message and fields to the provided base at the provided level.
+// If there are fields and the base doesn't implement FieldRequestLoggerBase, the fields are appended to the message.
+func logToBase(b RequestLoggerBase, level logLevel, msg string, fields map[string]interface{}) {
+	if len(fields) == 0 {
+		switch level {
+		case logLevelInfo:
+			b.Info(msg)
+		case logLevelWarning:
+			b.Warning(msg)
+		case logLevelError:
+			b.Error(msg)
+		case logLevelWTF:
+			b.WTF(msg)
+		}
+		return
+	}
+
+	fb, ok := b.(FieldRequestLoggerBase)
+	if !ok {
+		logToBase(b, level, msg+" "+formatFields(fields), nil)
+		return
+	}
+	switch level {
+	case logLevelInfo:
+		fb.InfoFields(msg, fields)
+	case logLevelWarning:
+		fb.WarningFields(msg, fields)
+	case logLevelError:
+		fb.ErrorFields(msg, fields)
+	case logLevelWTF:
+		fb.WTFFields(msg, fields)
+	}
+}
+
+// formatFields formats the fields as space-separated key=value pairs, sorted by key.
+func formatFields(fields map[string]interface{}) (formatted string) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, fields[k])
+	}
+	return strings.Join(pairs, " ")
+}
+
+const (
+	// stackTraceInitialSize is the size of the buffer initially used to capture stack traces.
+	stackTraceInitialSize = 8 * 1024
+	// stackTraceMaxSize is the maximum size of a captured stack trace. Larger traces are truncated.
+	stackTraceMaxSize = 1024 * 1024
+)
+
+// stackTrace returns the stack trace of the current goroutine.
+// The buffer starts small and doubles until the whole trace fits, up to stackTraceMaxSize.
+func stackTrace() (trace []byte) {
+	buf := make([]byte, stackTraceInitialSize)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) || len(buf) >= stackTraceMaxSize {
+			return buf[:n]
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
 // RequestLogger is used to log events that occur within a request handler.
 type RequestLogger struct {
-	b RequestLoggerBase
+	b          RequestLoggerBase
+	fields     map[string]interface{}
+	panicOnWTF bool
+	// highest is the highest level logged so far plus one, so that 0 means nothing has been logged. It's shared by copies of the logger.
+	highest *int32
+}
+
+// record notes that a log has been written at the provided level, for use by highestLevel.
+func (rl *RequestLogger) record(level logLevel) {
+	if rl.highest == nil {
+		return
+	}
+	for {
+		current := atomic.LoadInt32(rl.highest)
+		if current > int32(level) || atomic.CompareAndSwapInt32(rl.highest, current, int32(level)+1) {
+			return
+		}
+	}
+}
+
+// highestLevel returns the highest level that has been logged for the request, and whether anything has been logged at all.
+func (rl *RequestLogger) highestLevel() (level logLevel, ok bool) {
+	if rl.highest == nil {
+		return 0, false
+	}
+	highest := atomic.LoadInt32(rl.highest)
+	return logLevel(highest - 1), highest != 0
+}
+
+// With returns a copy of the logger which attaches the provided key-value field to every log.
+func (rl *RequestLogger) With(key string, value interface{}) (l *RequestLogger) {
+	return rl.WithFields(map[string]interface{}{key: value})
+}
+
+// WithFields returns a copy of the logger which attaches the provided key-value fields to every log, in addition to any fields already attached.
+func (rl *RequestLogger) WithFields(fields map[string]interface{}) (l *RequestLogger) {
+	l = &RequestLogger{}
+	*l = *rl
+	l.fields = make(map[string]interface{}, len(rl.fields)+len(fields))
+	for k, v := range rl.fields {
+		l.fields[k] = v
+	}
+	for k, v := range fields {
+		l.fields[k] = v
+	}
+	return
 }
 
 // Info should be used to log things that are useful to know, but not in any way bad.
 func (rl *RequestLogger) Info(msg string) {
-	rl.b.Info(msg)
+	rl.record(logLevelInfo)
+	logToBase(rl.b, logLevelInfo, msg, rl.fields)
 }
 
 // Infof formats your message before logging it as Info, using the provided FormatLog function.
@@ -38,7 +179,8 @@ func (rl *RequestLogger) Infof(format string, values ...interface{}) {
 
 // Warning should be used to log problems that are not bad enough to make the request completely fail.
 func (rl *RequestLogger) Warning(msg string) {
-	rl.b.Warning(msg)
+	rl.record(logLevelWarning)
+	logToBase(rl.b, logLevelWarning, msg, rl.fields)
 }
 
 // Warningf formats your message before logging it as a Warning, using the provided FormatLog function.
@@ -48,7 +190,8 @@ func (rl *RequestLogger) Warningf(format string, values ...interface{}) {
 
 // Error should be used to log problems that are bad enough to make the request completely fail.
 func (rl *RequestLogger) Error(msg string) {
-	rl.b.Error(msg)
+	rl.record(logLevelError)
+	logToBase(rl.b, logLevelError, msg, rl.fields)
 }
 
 // Errorf formats your message before logging it as an Error, using the provided FormatLog function.
@@ -59,11 +202,24 @@ func (rl *RequestLogger) Errorf(format string, values ...interface{}) {
 // WTF should be used to log problems that should absolutely never happen.
 // This also records a stack trace as a second WTF event.
 // This should be indicative of a programming bug, as opposed to an expected runtime error.
+// If the server's DevelopmentMode is enabled, this panics after logging.
 func (rl *RequestLogger) WTF(msg string) {
-	rl.b.WTF(msg)
-	stBuf := make([]byte, 100000)
-	n := runtime.Stack(stBuf, false)
-	rl.b.WTF("Stack Trace:\n" + string(stBuf[:n]))
+	rl.logWTF(msg)
+	if rl.panicOnWTF {
+		panic("WTF: " + msg)
+	}
+}
+
+// logWTF logs the message and a stack trace as WTF events, without panicking in development mode.
+func (rl *RequestLogger) logWTF(msg string) {
+	rl.logWTFStack(msg, stackTrace())
+}
+
+// logWTFStack is the same as logWTF, using the provided stack trace, such as one captured in another goroutine.
+func (rl *RequestLogger) logWTFStack(msg string, trace []byte) {
+	rl.record(logLevelWTF)
+	logToBase(rl.b, logLevelWTF, msg, rl.fields)
+	logToBase(rl.b, logLevelWTF, "Stack Trace:\n"+string(trace), rl.fields)
 }
 
 // WTFf formats your message before logging it as WTF, using the provided FormatLog function.
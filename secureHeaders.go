@@ -0,0 +1,88 @@
+package lightwork
+
+import (
+	"strconv"
+	"time"
+)
+
+// SecureHeadersOptions configures the SecureHeaders middleware.
+// The zero value sends X-Content-Type-Options, X-Frame-Options, Referrer-Policy, and, over HTTPS, Strict-Transport-Security, using the defaults described below.
+type SecureHeadersOptions struct {
+	// DisableContentTypeNosniff prevents the "X-Content-Type-Options: nosniff" header from being sent.
+	DisableContentTypeNosniff bool
+
+	// FrameOptions is the value of the X-Frame-Options header. If empty, "DENY" is used.
+	FrameOptions string
+	// DisableFrameOptions prevents the X-Frame-Options header from being sent.
+	DisableFrameOptions bool
+
+	// ReferrerPolicy is the value of the Referrer-Policy header. If empty, "strict-origin-when-cross-origin" is used.
+	ReferrerPolicy string
+	// DisableReferrerPolicy prevents the Referrer-Policy header from being sent.
+	DisableReferrerPolicy bool
+
+	// HSTSMaxAge is how long clients should only use HTTPS for the host, as sent in the Strict-Transport-Security header. If it's 0, a year is used.
+	HSTSMaxAge time.Duration
+	// HSTSIncludeSubdomains adds the includeSubDomains directive to the Strict-Transport-Security header.
+	HSTSIncludeSubdomains bool
+	// HSTSPreload adds the preload directive to the Strict-Transport-Security header.
+	HSTSPreload bool
+	// DisableHSTS prevents the Strict-Transport-Security header from being sent.
+	DisableHSTS bool
+
+	// ContentSecurityPolicy is the value of the Content-Security-Policy header. If empty, the header isn't sent.
+	ContentSecurityPolicy string
+}
+
+// hstsValue returns the value that should be used for the Strict-Transport-Security header.
+func (opts SecureHeadersOptions) hstsValue() (value string) {
+	maxAge := opts.HSTSMaxAge
+	if maxAge == 0 {
+		maxAge = 365 * 24 * time.Hour
+	}
+	value = "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	if opts.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if opts.HSTSPreload {
+		value += "; preload"
+	}
+	return
+}
+
+// SecureHeaders returns middleware that adds common security headers to every response.
+// The headers are set before the next handler runs, so they're sent even if the response is streamed. Headers that have already been set, such as by earlier middleware, are left as they are, and handlers can override any of them by setting the header themselves.
+// Strict-Transport-Security is only sent when the request's scheme is https, as reported by c.Request.Scheme, since clients ignore it over plain HTTP.
+func SecureHeaders(opts SecureHeadersOptions) Middleware {
+	frameOptions := opts.FrameOptions
+	if frameOptions == "" {
+		frameOptions = "DENY"
+	}
+	referrerPolicy := opts.ReferrerPolicy
+	if referrerPolicy == "" {
+		referrerPolicy = "strict-origin-when-cross-origin"
+	}
+	hsts := opts.hstsValue()
+
+	return func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			cr := c.Response
+			if !opts.DisableContentTypeNosniff {
+				cr.setHeaderIfNotAlreadySet("X-Content-Type-Options", "nosniff")
+			}
+			if !opts.DisableFrameOptions {
+				cr.setHeaderIfNotAlreadySet("X-Frame-Options", frameOptions)
+			}
+			if !opts.DisableReferrerPolicy {
+				cr.setHeaderIfNotAlreadySet("Referrer-Policy", referrerPolicy)
+			}
+			if !opts.DisableHSTS && c.Request.Scheme() == "https" {
+				cr.setHeaderIfNotAlreadySet("Strict-Transport-Security", hsts)
+			}
+			if opts.ContentSecurityPolicy != "" {
+				cr.setHeaderIfNotAlreadySet("Content-Security-Policy", opts.ContentSecurityPolicy)
+			}
+			return next(c)
+		}
+	}
+}
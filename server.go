@@ -1,17 +1,51 @@
 package lightwork
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 
+	"net"
 	"net/http"
 	"net/http/httptest"
 )
 
+// MetricsObserver is notified of every completed request, allowing metrics such as request counts and latencies to be collected.
+type MetricsObserver interface {
+	// ObserveRequest is called once the handler has completed.
+	// The route is the pattern the handler was registered with, such as /users/:id, rather than the request path, to keep label cardinality low.
+	// Requests that didn't match a route, such as those handled by the NotFound handler, have an empty route.
+	ObserveRequest(route, method string, status int, duration time.Duration)
+}
+
 type Server struct {
 	router *httprouter.Router
 
+	routesMutex sync.Mutex
+	routes      []RouteInfo
+
+	httpServerMutex sync.Mutex
+	httpServer      *http.Server
+
+	globalMiddleware []Middleware
+	globalHandler    Handler
+
+	optionsHandlers []optionsHandler
+
+	startHooks    []func() error
+	shutdownHooks []func(ctx context.Context) error
+
 	// EncodeStructPreHook will be called before writing the header when using the struct encoder.
 	// This allows you to modify the header before it gets written, such as setting the Content-Type.
 	EncodeStructPreHook func(c *Context)
@@ -26,17 +60,362 @@ type Server struct {
 	// ValidateStruct will be used to validate objects.
 	ValidateStruct func(c *Context, input interface{}) (err error)
 
+	// Renderer will be used to render templates for ContextResponse.Render.
+	// NewTemplateRendererGlob and NewTemplateRendererFS provide a default implementation backed by html/template.
+	Renderer Renderer
+
+	// Tracer, if set, is used to start a tracing span for each request, which is ended once the request has completed.
+	// When global middleware is used, the span is started once the request has been routed, so the global middleware that runs beforehand isn't covered by it.
+	// Requests that the router responds to itself, such as 404s when no NotFound handler is set, aren't traced.
+	Tracer Tracer
+
+	// BaseContext, if set, is called at the beginning of every request to get the context used for the request's Context, in place of the request's own context.
+	// This can be used to inject request-independent values, or context established before the router, such as trace spans.
+	// The returned context should usually be derived from req.Context(), so that it's still cancelled when the client disconnects.
+	BaseContext func(req *http.Request) (ctx context.Context)
+
 	// NewRequestLogger will be called at the beginning of every request to get a logger to be used for that request.
 	NewRequestLogger func(c *Context) (rlb RequestLoggerBase)
 
+	// MaxBodyBytes limits the size of request bodies that can be read by handlers.
+	// Reading beyond the limit will return ErrBodyTooLarge, which handlers can use to respond with a 413.
+	// The limit is applied to the *http.Request's body using http.MaxBytesReader, so it also applies to bodies read through EscapeHatch, and the connection is closed once the response has been written.
+	// If it's 0, request bodies are unlimited.
+	MaxBodyBytes int64
+
+	// ExpectedRequestContentType, if set, is the media type that BodyStruct and BodyStructValidated require the request's Content-Type to have, such as "application/json".
+	// Parameters such as charset are ignored. If the Content-Type doesn't match, ErrUnsupportedMediaType is returned, which is responded to with a 415 unless the response has already been written.
+	ExpectedRequestContentType string
+
+	// AccessLogger will be called once the handler has completed, before the request logs are written, to log the outcome of the request.
+	// NewServer sets this to DefaultAccessLogger. If it's nil, no access log is written.
+	AccessLogger func(c *Context, status int, bytes int, duration time.Duration)
+
+	// MetricsObserver will be notified once every request has completed, if configured.
+	MetricsObserver MetricsObserver
+
+	// ErrorHandler will be called whenever a handler returns a non-nil error, after the error has been logged.
+	// This allows errors to be translated into HTTP responses in one place.
+	// The response may have already been written by the handler, which can be checked using c.Response.GetStatusCode.
+	// If no response has been written after this is called, and the error is an *HTTPError, the HTTPError will be used to write the response.
+	ErrorHandler func(c *Context, err error)
+
 	// ClientHost will be called to determine the hostname or IP address of the client making the request.
+	// If it's nil, ContextRequest.ClientIP is used.
 	ClientHost func(c *Context) (host string)
+
+	// TrustProxyHeaders enables the use of X-Forwarded-For, X-Real-IP, and X-Forwarded-Proto to determine the client IP and scheme.
+	// This should only be enabled when the server is behind a proxy that sets these headers, since otherwise clients can spoof them.
+	TrustProxyHeaders bool
+	// TrustedProxies are the networks of the proxies in front of the server, used by ContextRequest.ClientIP to skip over proxy addresses in X-Forwarded-For.
+	// If it's empty, only the proxy closest to the server is trusted, so the right-most address in X-Forwarded-For is used.
+	TrustedProxies []*net.IPNet
+
+	// FileETag controls how ETags are generated for files served by ContextResponse.File.
+	// The default is ETagWeak, which is cheap to compute. ETagDisabled can be used to avoid hashing very large files when using ETagStrong.
+	FileETag ETagMode
+
+	// LogFilter, if set, is called once each request has completed, to decide whether its access log and request logs should be written.
+	// Returning false suppresses them, which is useful for noisy endpoints such as health checks. Requests that logged a WTF, such as for a recovered panic, are always logged.
+	// Logs are still passed to the RequestLoggerBase as they happen, so this only has an effect if the base defers writing them until WriteLogs is called.
+	LogFilter func(c *Context) (log bool)
+
+	// LogSampleRate, if between 0 and 1, is the fraction of successful requests whose access log and request logs are written.
+	// Requests that return an error, respond with a 4xx or 5xx status code, or log a warning or worse are always logged. If it's 0, every request is logged.
+	LogSampleRate float64
+
+	// DevelopmentMode causes RequestLogger.WTF to panic after logging, so that bugs surface loudly during development and testing.
+	// When disabled, WTF events are only logged.
+	DevelopmentMode bool
+
+	// HandleOPTIONS enables automatic responses to OPTIONS requests, for paths that don't have an OPTIONS route registered.
+	// The response includes the Allow header, and can be customised using SetOPTIONSHandler.
+	// NewServer enables this by default. Changes take effect when the server is started.
+	// Automatic responses are disabled if either this or the underlying Router's HandleOPTIONS is disabled, so disabling it through Router still works.
+	HandleOPTIONS bool
+
+	// AutoHEAD causes every GET route to also be registered as a HEAD route, which runs the same handler with the response body discarded by net/http.
+	// Headers are sent as they would be for a GET request, including the Content-Length that net/http sets for short bodies.
+	// When enabled, HEAD routes shouldn't be registered separately for paths that have GET routes.
+	AutoHEAD bool
+
+	// ReadHeaderTimeout bounds how long the client is given to send the request headers, which protects against slow-loris style connections.
+	// NewServer sets this to 10 seconds. If it's 0, ReadTimeout is used instead.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout bounds how long the client is given to send the whole request, including the body.
+	// NewServer sets this to 30 seconds. If it's 0, there is no limit.
+	ReadTimeout time.Duration
+	// WriteTimeout bounds how long the server is given to write the response, from when the request headers have been read.
+	// NewServer sets this to 30 seconds. If it's 0, there is no limit.
+	// Long-lived responses such as streams and server-sent events are cut off once it expires, so servers with such handlers should set it to 0, and use the Timeout middleware to bound other handlers instead.
+	WriteTimeout time.Duration
+	// IdleTimeout bounds how long keep-alive connections are kept open while waiting for the next request.
+	// NewServer sets this to 2 minutes. If it's 0, ReadTimeout is used instead.
+	IdleTimeout time.Duration
+
+	// ShutdownTimeout bounds how long in-flight requests are given to complete when the context passed to StartWithContext is cancelled.
+	// If it's 0, the server will wait for all in-flight requests to complete.
+	ShutdownTimeout time.Duration
 }
 
 func NewServer() (server *Server) {
 	return &Server{
-		router: httprouter.New(),
+		router:        httprouter.New(),
+		AccessLogger:  DefaultAccessLogger,
+		HandleOPTIONS: true,
+
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+}
+
+// DefaultAccessLogger logs the method, path, status code, response size, and duration of the request as Info.
+func DefaultAccessLogger(c *Context, status int, bytes int, duration time.Duration) {
+	c.Log.Infof("%s %s %d %dB %v", c.Request.Method(), c.Request.URL().Path, status, bytes, duration)
+}
+
+// routerHandle converts a Handler for the provided route pattern into an httprouter.Handle, which serves the request using serveContext.
+func (s *Server) routerHandle(route string, h Handler) httprouter.Handle {
+	return func(rw http.ResponseWriter, req *http.Request, p httprouter.Params) {
+		s.serveContext(h, route, rw, req, p)
+	}
+}
+
+// serveContext builds a Context for the request, runs the provided handler with it, then writes the access log and request logs.
+// The route is the pattern the handler was registered with, or empty if the request didn't match a route.
+// If the request is already being served by the global middleware, its Context is reused instead, and the global middleware is responsible for completing the request.
+func (s *Server) serveContext(h Handler, route string, rw http.ResponseWriter, req *http.Request, p httprouter.Params) {
+	if gc, ok := req.Context().Value(globalContextKey{}).(*globalContext); ok {
+		gc.handled = true
+		gc.c.Request.params = p
+		gc.c.Request.route = route
+		gc.c.paramsMap = nil
+		gc.err = h(s.startSpan(gc.c, route))
+		return
+	}
+
+	start := time.Now()
+	c := s.newContext(rw, req, p, route)
+	// If the handler panics, the OnComplete callbacks are still run and the span is still ended, before the panic continues.
+	defer c.endSpan()
+	defer c.runOnComplete()
+	tc := s.startSpan(c, route)
+	err := h(tc)
+	s.completeRequest(tc, err, start)
+}
+
+// newContext builds the Context for a request.
+func (s *Server) newContext(rw http.ResponseWriter, req *http.Request, p httprouter.Params, route string) (c *Context) {
+	ctx := req.Context()
+	if s.BaseContext != nil {
+		ctx = s.BaseContext(req)
+	}
+	c = &Context{
+		server:  s,
+		Context: SimpleCtx{Context: ctx},
+	}
+	c.Response = ContextResponse{c: c, rw: &loggingResponseWriter{rw: rw}}
+	if s.MaxBodyBytes > 0 && req.Body != nil {
+		req.Body = newLimitedBody(rw, req.Body, s.MaxBodyBytes)
+	}
+	c.Request = ContextRequest{c: c, req: req, params: p, route: route}
+	rlb := s.NewRequestLogger(c)
+	c.Log = &RequestLogger{
+		b:          rlb,
+		panicOnWTF: s.DevelopmentMode,
+		highest:    new(int32),
+	}
+	c.Response.rw.log = c.Log
+	return
+}
+
+// completeRequest ends any response buffering that's still active, handles the error returned by the handler, ensures a response has been written, then writes the access log, runs the OnComplete callbacks, and writes the request logs, unless they're filtered out.
+func (s *Server) completeRequest(c *Context, err error, start time.Time) {
+	if c.buffer != nil {
+		c.Response.endBuffer(err != nil)
+	}
+	if err != nil {
+		s.handleError(c, err)
+	}
+	// WTFs raised here are only logged, and the panic for DevelopmentMode is deferred until the response and logs have been written.
+	wtf := ""
+	if c.Response.GetStatusCode() == 0 && !c.Response.rw.hijacked {
+		if c.Response.Size() == 0 {
+			wtf = "Handler didn't write a response"
+			c.Log.logWTF(wtf)
+			c.Response.Status(500)
+		} else {
+			c.Response.rw.statusCode = 200
+		}
+	}
+	duration := time.Since(start)
+	if s.MetricsObserver != nil {
+		s.MetricsObserver.ObserveRequest(c.Request.route, c.Request.Method(), c.Response.GetStatusCode(), duration)
+	}
+	writeLogs := s.shouldWriteLogs(c, err)
+	if writeLogs && s.AccessLogger != nil {
+		s.AccessLogger(c, c.Response.GetStatusCode(), int(c.Response.Size()), duration)
+	}
+	c.runOnComplete()
+	if writeLogs {
+		c.Log.b.WriteLogs()
+	}
+	if wtf != "" && c.Log.panicOnWTF {
+		c.Response.Flush()
+		panic("WTF: " + wtf)
+	}
+}
+
+// shouldWriteLogs decides whether the access log and request logs should be written for the completed request, based on the LogFilter and LogSampleRate.
+func (s *Server) shouldWriteLogs(c *Context, err error) (writeLogs bool) {
+	level, logged := c.Log.highestLevel()
+	if logged && level >= logLevelWTF {
+		return true
+	}
+	if s.LogFilter != nil && !s.LogFilter(c) {
+		return false
+	}
+	if s.LogSampleRate <= 0 || s.LogSampleRate >= 1 {
+		return true
+	}
+	failed := err != nil || c.Response.GetStatusCode() >= 400 || (logged && level >= logLevelWarning)
+	return failed || rand.Float64() < s.LogSampleRate
+}
+
+// globalContextKey is the request context key under which the globalContext is stored while the router is serving the request.
+type globalContextKey struct{}
+
+// globalContext passes the Context from the global middleware through the router to the route handler, and the handler's error back.
+type globalContext struct {
+	c       *Context
+	handled bool
+	err     error
+}
+
+// GlobalMiddleware registers one or more middleware handlers that wrap the whole router.
+// Unlike HandlerGroup middleware, global middleware runs for every request, including those handled by the NotFound and MethodNotAllowed handlers, and automatic OPTIONS responses.
+// Global middleware runs before any HandlerGroup middleware, and shares the same Context. Errors returned by route handlers are passed back through the global middleware.
+// Middleware is called in the order that it gets registered.
+func (s *Server) GlobalMiddleware(m ...Middleware) {
+	s.globalMiddleware = append(s.globalMiddleware, m...)
+	h := Handler(s.routeGlobal)
+	for i := len(s.globalMiddleware) - 1; i >= 0; i-- {
+		h = s.globalMiddleware[i](h)
+	}
+	s.globalHandler = h
+}
+
+// routeGlobal is the innermost global handler, which dispatches the request to the router using the provided Context.
+func (s *Server) routeGlobal(c *Context) (err error) {
+	gc := &globalContext{c: c}
+	req := c.Request.req.WithContext(context.WithValue(c.Request.req.Context(), globalContextKey{}, gc))
+	s.router.ServeHTTP(c.Response.rw, req)
+	if !gc.handled && c.Response.GetStatusCode() == 0 && c.Response.Size() == 0 {
+		// The router responded itself without writing anything, such as for automatic OPTIONS responses, which net/http sends as a 200.
+		return c.Response.Status(http.StatusOK)
+	}
+	return gc.err
+}
+
+// ServeHTTP serves the request using the router, wrapped in the global middleware, if any.
+func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if s.globalHandler == nil {
+		s.router.ServeHTTP(rw, req)
+		return
+	}
+
+	start := time.Now()
+	c := s.newContext(rw, req, nil, "")
+	defer c.endSpan()
+	defer c.runOnComplete()
+	err := s.globalHandler(c)
+	s.completeRequest(c, err, start)
+}
+
+// SetNotFoundHandler registers the handler used when no route matches the request.
+// The handler runs outside of any HandlerGroup, so group middleware does not apply to it, although global middleware does.
+func (s *Server) SetNotFoundHandler(h Handler) {
+	s.router.NotFound = s.classicHandler(h)
+}
+
+// SetMethodNotAllowedHandler registers the handler used when a route matches the request path, but not the request method.
+// The allowed methods are available in the Allow response header, via c.Response.Header().Get("Allow").
+// The handler runs outside of any HandlerGroup, so group middleware does not apply to it, although global middleware does.
+func (s *Server) SetMethodNotAllowedHandler(h Handler) {
+	s.router.MethodNotAllowed = s.classicHandler(h)
+}
+
+// optionsHandler is an OPTIONS handler registered for the paths under a base path.
+type optionsHandler struct {
+	basePath string
+	h        Handler
+}
+
+// SetOPTIONSHandler registers the handler used for automatic OPTIONS responses, for paths that don't have an OPTIONS route registered.
+// The allowed methods for the path are available in the Allow response header, via c.Response.Header().Get("Allow"), and the handler can modify the response as needed.
+// HandlerGroup.SetOPTIONSHandler can be used to register handlers scoped to a group instead, which take precedence for paths under the group's base path.
+// If the CORS middleware is registered as global middleware, it responds to preflight requests before this handler is reached.
+func (s *Server) SetOPTIONSHandler(h Handler) {
+	s.setOPTIONSHandler("", bufferScope(h))
+}
+
+// setOPTIONSHandler registers the OPTIONS handler for the provided base path, replacing any existing handler for the same base path.
+func (s *Server) setOPTIONSHandler(basePath string, h Handler) {
+	s.router.GlobalOPTIONS = http.HandlerFunc(s.serveOPTIONS)
+	for i := range s.optionsHandlers {
+		if s.optionsHandlers[i].basePath == basePath {
+			s.optionsHandlers[i].h = h
+			return
+		}
 	}
+	s.optionsHandlers = append(s.optionsHandlers, optionsHandler{basePath: basePath, h: h})
+}
+
+// serveOPTIONS serves an automatic OPTIONS response using the handler registered for the most specific base path matching the request.
+// If no handler matches, the router's default response is used.
+func (s *Server) serveOPTIONS(rw http.ResponseWriter, req *http.Request) {
+	var best *optionsHandler
+	for i, oh := range s.optionsHandlers {
+		base := strings.TrimSuffix(oh.basePath, "/")
+		matches := base == "" || req.URL.Path == base || strings.HasPrefix(req.URL.Path, base+"/")
+		if matches && (best == nil || len(oh.basePath) > len(best.basePath)) {
+			best = &s.optionsHandlers[i]
+		}
+	}
+	if best == nil {
+		return
+	}
+	s.serveContext(best.h, "", rw, req, nil)
+}
+
+// classicHandler converts a Handler into a classic Go http.Handler, for use outside of a registered route.
+func (s *Server) classicHandler(h Handler) http.Handler {
+	h = bufferScope(h)
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		s.serveContext(h, "", rw, req, nil)
+	})
+}
+
+// RouteInfo describes a route registered using a HandlerGroup.
+type RouteInfo struct {
+	// Method is the HTTP Method of the route.
+	Method string
+	// Path is the full route pattern, including the group's base path, such as "/users/:id".
+	Path string
+	// GroupPath is the base path of the HandlerGroup that registered the route.
+	GroupPath string
+}
+
+// Routes returns all of the routes registered using HandlerGroups, in the order they were registered.
+// This includes the HEAD routes registered for GET routes when AutoHEAD is enabled, but not routes registered directly on the underlying Router.
+func (s *Server) Routes() (routes []RouteInfo) {
+	s.routesMutex.Lock()
+	defer s.routesMutex.Unlock()
+	routes = make([]RouteInfo, len(s.routes))
+	copy(routes, s.routes)
+	return
 }
 
 // Router returns the underlying julienschmidt/httprouter Router instance.
@@ -61,11 +440,153 @@ func (s *Server) AddHandlerGroup(basePath string, registerFunc func(hg *HandlerG
 }
 
 // Start listens on the provided address, and starts serving requests.
+// It blocks until the server fails, or is shut down using Shutdown.
 func (s *Server) Start(address string) (err error) {
-	return http.ListenAndServe(address, s.router)
+	return s.StartWithContext(context.Background(), address)
+}
+
+// StartWithContext listens on the provided address, and starts serving requests.
+// When the provided context is cancelled, the server is gracefully shut down, bounded by the ShutdownTimeout.
+// It blocks until the server fails, or has been shut down.
+func (s *Server) StartWithContext(ctx context.Context, address string) (err error) {
+	srv := s.newHTTPServer(address)
+	return s.serve(ctx, s.ShutdownTimeout, srv.ListenAndServe)
+}
+
+// StartTLS listens on the provided address, and starts serving HTTPS requests using the provided certificate and key files.
+// HTTP/2 is enabled automatically by Go when using TLS; streaming responses continue to work as they do over HTTP/1.1.
+// It blocks until the server fails, or is shut down using Shutdown.
+func (s *Server) StartTLS(address, certFile, keyFile string) (err error) {
+	srv := s.newHTTPServer(address)
+	return s.serve(context.Background(), s.ShutdownTimeout, func() error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+// StartTLSConfig listens on the provided address, and starts serving HTTPS requests using the provided TLS config.
+// The config must provide certificates, either via Certificates or GetCertificate, which allows the use of something like autocert.
+// It blocks until the server fails, or is shut down using Shutdown.
+func (s *Server) StartTLSConfig(address string, cfg *tls.Config) (err error) {
+	srv := s.newHTTPServer(address)
+	srv.TLSConfig = cfg
+	return s.serve(context.Background(), s.ShutdownTimeout, func() error {
+		return srv.ListenAndServeTLS("", "")
+	})
+}
+
+// Run listens on the provided address, and starts serving requests, until the process receives an interrupt or termination signal.
+// The server is then gracefully shut down, giving in-flight requests up to the provided timeout to complete, and the OnShutdown hooks are run.
+// If the timeout is 0, the server will wait for all in-flight requests to complete.
+// It blocks until the server fails, or has been shut down.
+func (s *Server) Run(address string, shutdownTimeout time.Duration) (err error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := s.newHTTPServer(address)
+	return s.serve(ctx, shutdownTimeout, srv.ListenAndServe)
+}
+
+// RunTLS is the same as Run, except that it serves HTTPS requests using the provided certificate and key files, as per StartTLS.
+func (s *Server) RunTLS(address, certFile, keyFile string, shutdownTimeout time.Duration) (err error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := s.newHTTPServer(address)
+	return s.serve(ctx, shutdownTimeout, func() error {
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	})
+}
+
+// Shutdown gracefully shuts down the server, waiting for in-flight requests to complete, then runs the OnShutdown hooks in order.
+// If the provided context expires before all requests have completed, the context's error is returned.
+// The hooks are run regardless of whether the server shut down cleanly, and the first error is returned.
+func (s *Server) Shutdown(ctx context.Context) (err error) {
+	s.httpServerMutex.Lock()
+	srv := s.httpServer
+	s.httpServerMutex.Unlock()
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+
+	for _, hook := range s.shutdownHooks {
+		hookErr := hook(ctx)
+		if hookErr != nil && err == nil {
+			err = fmt.Errorf("shutdown hook failed: %w", hookErr)
+		}
+	}
+	return
+}
+
+// OnStart registers a hook to be run when the server starts, before it begins listening.
+// Hooks are run in the order they're registered. If a hook returns an error, startup is aborted and the error is returned.
+func (s *Server) OnStart(hook func() error) {
+	s.startHooks = append(s.startHooks, hook)
+}
+
+// OnShutdown registers a hook to be run when the server is shut down, after in-flight requests have completed.
+// Hooks are run in the order they're registered, with the context used for the shutdown.
+func (s *Server) OnShutdown(hook func(ctx context.Context) error) {
+	s.shutdownHooks = append(s.shutdownHooks, hook)
+}
+
+// prepareRouter applies the server's configuration to the router before it starts serving.
+func (s *Server) prepareRouter() {
+	if !s.HandleOPTIONS {
+		s.router.HandleOPTIONS = false
+	}
+}
+
+// newHTTPServer creates the underlying http.Server used to serve requests, using the server's timeouts, and stores it so that it can later be shut down.
+func (s *Server) newHTTPServer(address string) (srv *http.Server) {
+	s.prepareRouter()
+	srv = &http.Server{
+		Addr:              address,
+		Handler:           s,
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+		ReadTimeout:       s.ReadTimeout,
+		WriteTimeout:      s.WriteTimeout,
+		IdleTimeout:       s.IdleTimeout,
+	}
+	s.httpServerMutex.Lock()
+	s.httpServer = srv
+	s.httpServerMutex.Unlock()
+	return
+}
+
+// serve runs the OnStart hooks, then runs the provided listen function until it fails, or until the context is cancelled, in which case the server is shut down, bounded by the shutdown timeout.
+func (s *Server) serve(ctx context.Context, shutdownTimeout time.Duration, listen func() error) (err error) {
+	for _, hook := range s.startHooks {
+		err = hook()
+		if err != nil {
+			return fmt.Errorf("start hook failed: %w", err)
+		}
+	}
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- listen()
+	}()
+
+	select {
+	case err = <-listenErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return
+	case <-ctx.Done():
+	}
+
+	shutdownCtx := context.Background()
+	if shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, shutdownTimeout)
+		defer cancel()
+	}
+	err = s.Shutdown(shutdownCtx)
+	<-listenErr
+	return
 }
 
 // StartTest starts and returns an *httptest.Server, which can be used for automated testing
 func (s *Server) StartTest() (testServer *httptest.Server) {
-	return httptest.NewServer(s.router)
+	s.prepareRouter()
+	return httptest.NewServer(s)
 }
@@ -0,0 +1,50 @@
+package lightwork
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPServerTimeouts(t *testing.T) {
+	s := newTestServer()
+	s.ReadHeaderTimeout = 1 * time.Second
+	s.ReadTimeout = 2 * time.Second
+	s.WriteTimeout = 3 * time.Second
+	s.IdleTimeout = 4 * time.Second
+
+	srv := s.newHTTPServer(":0")
+	if srv.ReadHeaderTimeout != s.ReadHeaderTimeout {
+		t.Errorf("expected ReadHeaderTimeout %v, got %v", s.ReadHeaderTimeout, srv.ReadHeaderTimeout)
+	}
+	if srv.ReadTimeout != s.ReadTimeout {
+		t.Errorf("expected ReadTimeout %v, got %v", s.ReadTimeout, srv.ReadTimeout)
+	}
+	if srv.WriteTimeout != s.WriteTimeout {
+		t.Errorf("expected WriteTimeout %v, got %v", s.WriteTimeout, srv.WriteTimeout)
+	}
+	if srv.IdleTimeout != s.IdleTimeout {
+		t.Errorf("expected IdleTimeout %v, got %v", s.IdleTimeout, srv.IdleTimeout)
+	}
+}
+
+func TestNewServerDefaultTimeouts(t *testing.T) {
+	srv := NewServer().newHTTPServer(":0")
+	if srv.ReadHeaderTimeout == 0 || srv.ReadTimeout == 0 || srv.WriteTimeout == 0 || srv.IdleTimeout == 0 {
+		t.Fatalf("expected every timeout to have a default, got %v, %v, %v, %v", srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+}
+
+func TestRouterHandleOPTIONSDisabled(t *testing.T) {
+	s := newTestServer()
+	s.Router().HandleOPTIONS = false
+	s.GetHandlerGroup("").GET("/a", func(c *Context) (err error) {
+		return c.Response.Status(http.StatusOK)
+	})
+
+	rec := serveTest(s, httptest.NewRequest(http.MethodOptions, "/a", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected a 405 with automatic OPTIONS responses disabled, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,74 @@
+package lightwork
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SSEStream is used to send server-sent events to the client.
+type SSEStream struct {
+	cr ContextResponse
+}
+
+// SSE starts a server-sent events response, returning a stream that can be used to send events.
+// The headers are set and a 200 status code is written immediately.
+// An error is returned if the underlying writer doesn't support flushing, in which case nothing is written.
+// The stream is cut off once the server's WriteTimeout expires, so it should be set to 0 on servers that use long-lived streams.
+func (cr ContextResponse) SSE() (stream *SSEStream, err error) {
+	if !cr.rw.canFlush() {
+		return nil, errors.New("response writer does not support flushing")
+	}
+	header := cr.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Del("Content-Length")
+	cr.rw.WriteHeader(http.StatusOK)
+	cr.rw.Flush()
+	return &SSEStream{cr: cr}, nil
+}
+
+// Done returns a channel that is closed when the request's context is done, such as when the client disconnects.
+// This can be used to exit the event loop.
+func (s *SSEStream) Done() <-chan struct{} {
+	return s.cr.c.Context.Done()
+}
+
+// Send sends an event with the provided data, then flushes it to the client.
+// If event is empty, the event field is omitted, and the client will treat it as a message event.
+// Multi-line data is split into multiple data fields, as required by the format.
+// If the request's context is done, its error is returned without sending anything.
+func (s *SSEStream) Send(event, data string) (err error) {
+	err = s.cr.c.Context.Err()
+	if err != nil {
+		return
+	}
+
+	sb := strings.Builder{}
+	if event != "" {
+		sb.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+
+	_, err = s.cr.rw.Write([]byte(sb.String()))
+	if err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	s.cr.rw.Flush()
+	return nil
+}
+
+// SendJSON sends an event with the provided value serialised as JSON for the data, then flushes it to the client.
+func (s *SSEStream) SendJSON(event string, v interface{}) (err error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to serialise event data: %w", err)
+	}
+	return s.Send(event, string(data))
+}
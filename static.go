@@ -0,0 +1,119 @@
+package lightwork
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// staticIndexFile is the file served when a directory is requested.
+const staticIndexFile = "index.html"
+
+// cleanStaticPath converts the requested catch-all path into a slash-separated path relative to the root, without any leading slash.
+// An error is returned if the path attempts to traverse outside of the root.
+func cleanStaticPath(requested string) (cleaned string, err error) {
+	for _, segment := range strings.Split(strings.ReplaceAll(requested, "\\", "/"), "/") {
+		if segment == ".." {
+			return "", errors.New("path traversal is not allowed")
+		}
+	}
+	cleaned = strings.TrimPrefix(path.Clean("/"+requested), "/")
+	if cleaned == "" {
+		cleaned = "."
+	}
+	return
+}
+
+// setStaticContentType sets the Content-Type based on the file extension, if it's known and the header isn't already set.
+// Otherwise, Go will infer the Content-Type from the content of the file.
+func setStaticContentType(c *Context, name string) {
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType != "" {
+		c.Response.setHeaderIfNotAlreadySet("Content-Type", contentType)
+	}
+}
+
+// staticRoute returns the catch-all route pattern used for serving static files under the provided URL path.
+func staticRoute(urlPath string) (route string) {
+	return strings.TrimSuffix(urlPath, "/") + "/*filepath"
+}
+
+// registerStatic registers the handler for GET and HEAD requests on the catch-all route for the provided URL path.
+func (hg *HandlerGroup) registerStatic(urlPath string, h Handler) {
+	route := staticRoute(urlPath)
+	hg.GET(route, h)
+	if !hg.s.AutoHEAD {
+		fullPath := hg.basePath + route
+		hg.register(http.MethodHead, fullPath, hg.handlerShim(fullPath, h))
+	}
+}
+
+// Static serves the files within rootDir under the provided URL path, for GET and HEAD requests.
+// Files are served using ContextResponse.File, so middleware, logging, and Range requests all apply as normal.
+// Requests for a directory serve the index.html file within the directory, if present.
+// Paths attempting to traverse outside of rootDir are rejected with a 400, and missing files return a 404 *HTTPError.
+func (hg *HandlerGroup) Static(urlPath, rootDir string) {
+	hg.registerStatic(urlPath, func(c *Context) (err error) {
+		name, err := cleanStaticPath(c.Request.GetParam("filepath"))
+		if err != nil {
+			return NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		fullName := filepath.Join(rootDir, filepath.FromSlash(name))
+		info, err := os.Stat(fullName)
+		if err == nil && info.IsDir() {
+			fullName = filepath.Join(fullName, staticIndexFile)
+			info, err = os.Stat(fullName)
+		}
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+			return NewHTTPError(http.StatusNotFound, "")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stat static file: %w", err)
+		}
+
+		setStaticContentType(c, fullName)
+		return c.Response.File(http.StatusOK, fullName)
+	})
+}
+
+// StaticFS serves the files within fsys under the provided URL path, for GET and HEAD requests.
+// This is useful for serving embedded assets, using an embed.FS.
+// It otherwise behaves the same as Static, except that files not implementing io.Seeker can't support Range requests.
+func (hg *HandlerGroup) StaticFS(urlPath string, fsys fs.FS) {
+	hg.registerStatic(urlPath, func(c *Context) (err error) {
+		name, err := cleanStaticPath(c.Request.GetParam("filepath"))
+		if err != nil {
+			return NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		info, err := fs.Stat(fsys, name)
+		if err == nil && info.IsDir() {
+			name = path.Join(name, staticIndexFile)
+			info, err = fs.Stat(fsys, name)
+		}
+		if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+			return NewHTTPError(http.StatusNotFound, "")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to stat static file: %w", err)
+		}
+
+		file, err := fsys.Open(name)
+		if err != nil {
+			return fmt.Errorf("failed to open static file: %w", err)
+		}
+		defer file.Close()
+
+		setStaticContentType(c, name)
+		if rs, ok := file.(io.ReadSeeker); ok {
+			return c.Response.StreamReadSeeker(http.StatusOK, rs)
+		}
+		return c.Response.Stream(http.StatusOK, file)
+	})
+}
@@ -0,0 +1,260 @@
+package lightwork
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// timeoutWriter forwards writes to the underlying writer until the handler times out, after which writes are rejected.
+// Headers are buffered separately, so that a handler that is still running after the timeout can't modify the real headers.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	rw          http.ResponseWriter
+	h           http.Header
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header returns the buffered headers until the status code has been written, after which it returns the real headers so that trailers can be set.
+func (tw *timeoutWriter) Header() (h http.Header) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.wroteHeader && !tw.timedOut {
+		return tw.rw.Header()
+	}
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeHeader(statusCode)
+}
+
+// writeHeader copies the buffered headers to the underlying writer, then writes the status code.
+// Informational 1xx status codes other than 101 are passed through without committing the response, so that the final status code can still be written.
+// The mutex must be held when calling this.
+func (tw *timeoutWriter) writeHeader(statusCode int) {
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		tw.copyHeader()
+		tw.rw.WriteHeader(statusCode)
+		return
+	}
+	tw.wroteHeader = true
+	tw.copyHeader()
+	tw.rw.WriteHeader(statusCode)
+}
+
+// copyHeader replaces the underlying writer's headers with the buffered headers.
+// The mutex must be held when calling this.
+func (tw *timeoutWriter) copyHeader() {
+	header := tw.rw.Header()
+	for k := range header {
+		if _, ok := tw.h[k]; !ok {
+			delete(header, k)
+		}
+	}
+	for k, v := range tw.h {
+		header[k] = v
+	}
+}
+
+func (tw *timeoutWriter) Write(b []byte) (n int, err error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeader(http.StatusOK)
+	return tw.rw.Write(b)
+}
+
+// Flush flushes the underlying writer, unless the handler has timed out.
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	if f, ok := tw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// complete copies the buffered headers to the underlying writer if the handler completed without writing the status code, so that they're included in the response written for it, such as for a returned error.
+func (tw *timeoutWriter) complete() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.wroteHeader && !tw.timedOut {
+		tw.copyHeader()
+	}
+}
+
+// timeout marks the writer as timed out, and returns whether the handler had already started writing the response.
+func (tw *timeoutWriter) timeout() (wroteHeader bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	return tw.wroteHeader
+}
+
+// valuesCtx uses the values of one context, with the deadline and cancellation of another.
+// It allows values set by a handler on its timeout context to outlive the timeout.
+type valuesCtx struct {
+	context.Context
+	values context.Context
+}
+
+func (ctx valuesCtx) Value(key interface{}) (value interface{}) {
+	return ctx.values.Value(key)
+}
+
+// timeoutLoggerBase forwards logs to the underlying logger until the handler times out, after which logs are dropped.
+// This prevents a handler that is still running after the timeout from logging concurrently with the rest of the request.
+type timeoutLoggerBase struct {
+	mu       sync.Mutex
+	b        RequestLoggerBase
+	timedOut bool
+}
+
+func (tlb *timeoutLoggerBase) do(f func()) {
+	tlb.mu.Lock()
+	defer tlb.mu.Unlock()
+	if !tlb.timedOut {
+		f()
+	}
+}
+
+func (tlb *timeoutLoggerBase) Info(msg string)    { tlb.do(func() { tlb.b.Info(msg) }) }
+func (tlb *timeoutLoggerBase) Warning(msg string) { tlb.do(func() { tlb.b.Warning(msg) }) }
+func (tlb *timeoutLoggerBase) Error(msg string)   { tlb.do(func() { tlb.b.Error(msg) }) }
+func (tlb *timeoutLoggerBase) WTF(msg string)     { tlb.do(func() { tlb.b.WTF(msg) }) }
+func (tlb *timeoutLoggerBase) WriteLogs()         { tlb.do(func() { tlb.b.WriteLogs() }) }
+
+func (tlb *timeoutLoggerBase) InfoFields(msg string, fields map[string]interface{}) {
+	tlb.do(func() { logToBase(tlb.b, logLevelInfo, msg, fields) })
+}
+
+func (tlb *timeoutLoggerBase) WarningFields(msg string, fields map[string]interface{}) {
+	tlb.do(func() { logToBase(tlb.b, logLevelWarning, msg, fields) })
+}
+
+func (tlb *timeoutLoggerBase) ErrorFields(msg string, fields map[string]interface{}) {
+	tlb.do(func() { logToBase(tlb.b, logLevelError, msg, fields) })
+}
+
+func (tlb *timeoutLoggerBase) WTFFields(msg string, fields map[string]interface{}) {
+	tlb.do(func() { logToBase(tlb.b, logLevelWTF, msg, fields) })
+}
+
+func (tlb *timeoutLoggerBase) FormatLog(format string, values ...interface{}) (msg string) {
+	return tlb.b.FormatLog(format, values...)
+}
+
+func (tlb *timeoutLoggerBase) timeout() {
+	tlb.mu.Lock()
+	defer tlb.mu.Unlock()
+	tlb.timedOut = true
+}
+
+// timeoutPanic is a panic recovered from a handler run by Timeout, along with the stack trace of the goroutine that panicked.
+type timeoutPanic struct {
+	value interface{}
+	trace []byte
+}
+
+// logLatePanic logs a panic from a handler that had already timed out, which can no longer be propagated.
+func logLatePanic(log *RequestLogger, p timeoutPanic) {
+	log.logWTFStack(fmt.Sprintf("Handler panicked after timing out: %v", p.value), p.trace)
+}
+
+// Timeout returns middleware that limits the duration of later middleware and handlers.
+// The handler's context has the deadline applied, so downstream work that respects the context will be cancelled once it expires.
+// If the handler hasn't completed by the deadline, it is left running in the background, but its writes and logs are discarded.
+// In that case, if the handler hadn't started writing a response, a 503 *HTTPError is returned.
+// A panic in the handler is still propagated, as long as it occurs before the deadline. Panics after the deadline are logged as a WTF using the request's logger instead, without panicking, even in DevelopmentMode.
+// If the handler completes in time, its changes to the Context are kept, including headers set without writing a response, values set on the context, and OnComplete callbacks.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) (err error) {
+			ctx, cancel := context.WithTimeout(c.Context.Context, d)
+			defer cancel()
+
+			tw := &timeoutWriter{rw: c.Response.rw, h: c.Response.Header().Clone()}
+			tlb := &timeoutLoggerBase{b: c.Log.b}
+
+			// The handler runs with its own copy of the Context, so that it can't race with this request once timed out.
+			hc := *c
+			hc.Context.Context = ctx
+			hc.Response.c = &hc
+			hc.Response.rw = &loggingResponseWriter{rw: tw}
+			hc.Request.c = &hc
+			hl := *c.Log
+			hl.b = tlb
+			hc.Log = &hl
+			hc.Response.rw.log = hc.Log
+
+			log := c.Log
+			done := make(chan error, 1)
+			panicked := make(chan timeoutPanic, 1)
+			go func() {
+				defer func() {
+					r := recover()
+					if r == nil {
+						return
+					}
+					p := timeoutPanic{value: r, trace: stackTrace()}
+					tlb.mu.Lock()
+					defer tlb.mu.Unlock()
+					if tlb.timedOut {
+						logLatePanic(log, p)
+						return
+					}
+					panicked <- p
+				}()
+				done <- next(&hc)
+			}()
+
+			select {
+			case err = <-done:
+				// Everything the handler changed is kept, such as OnComplete callbacks and values set on the context, except for the timeout, and the writer and logger that guard against it.
+				tw.complete()
+				original := *c
+				*c = hc
+				c.Context = original.Context
+				if hc.Context.Context != ctx {
+					c.Context.Context = valuesCtx{Context: original.Context.Context, values: hc.Context.Context}
+				}
+				c.Response = original.Response
+				c.Request.c = c
+				c.Log = original.Log
+				return
+			case p := <-panicked:
+				panic(p.value)
+			case <-ctx.Done():
+			}
+
+			tlb.timeout()
+			// The handler may have panicked just before it was marked as timed out, in which case nothing else will read the panic.
+			select {
+			case p := <-panicked:
+				logLatePanic(log, p)
+			default:
+			}
+			wroteHeader := tw.timeout()
+			if wroteHeader {
+				return fmt.Errorf("handler timed out after %v, after the response had started", d)
+			}
+			return &HTTPError{
+				StatusCode: http.StatusServiceUnavailable,
+				Err:        fmt.Errorf("handler timed out after %v: %w", d, ctx.Err()),
+			}
+		}
+	}
+}
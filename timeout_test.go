@@ -0,0 +1,86 @@
+package lightwork
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger is a RequestLoggerBase that records the WTFs that are logged.
+type recordingLogger struct {
+	nopLogger
+	mu   sync.Mutex
+	wtfs []string
+}
+
+func (rl *recordingLogger) WTF(msg string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.wtfs = append(rl.wtfs, msg)
+}
+
+func (rl *recordingLogger) loggedWTF(substr string) (logged bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	for _, msg := range rl.wtfs {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTimeoutLogsPanicAfterDeadline(t *testing.T) {
+	s := newTestServer()
+	logger := &recordingLogger{}
+	s.NewRequestLogger = func(c *Context) RequestLoggerBase { return logger }
+	release := make(chan struct{})
+	hg := s.GetHandlerGroup("")
+	hg.AddMiddleware(Timeout(10 * time.Millisecond))
+	hg.GET("/", func(c *Context) (err error) {
+		<-release
+		panic("late failure")
+	})
+
+	rec := serveTest(s, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected a 503, got %d", rec.Code)
+	}
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !logger.loggedWTF("late failure") {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the panic after the deadline to be logged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !logger.loggedWTF("Stack Trace:") {
+		t.Fatal("expected the stack trace of the panic to be logged")
+	}
+}
+
+func TestTimeoutInformationalStatus(t *testing.T) {
+	s := newTestServer()
+	hg := s.GetHandlerGroup("")
+	hg.AddMiddleware(Timeout(time.Second))
+	hg.GET("/", func(c *Context) (err error) {
+		c.Response.Header().Set("Link", "</style.css>; rel=preload")
+		c.Response.rw.WriteHeader(http.StatusEarlyHints)
+		return c.Response.String(http.StatusCreated, "ok")
+	})
+	ts := s.StartTest()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected the final 201 after the 103, got %d", resp.StatusCode)
+	}
+}
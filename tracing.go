@@ -0,0 +1,42 @@
+package lightwork
+
+import (
+	"net/http"
+)
+
+// Tracer starts tracing spans for requests, allowing integration with distributed tracing systems such as OpenTelemetry without depending on them directly.
+type Tracer interface {
+	// StartSpan starts a span with the provided name, which is the matched route pattern, or the request method if the request didn't match a route.
+	// It should store the span in c.Context, such as by replacing c.Context.Context with a context containing it, so that it propagates to downstream calls made by the handler, then return the Context.
+	// The returned function is called with the final status code once the request has completed, to end the span.
+	StartSpan(c *Context, name string) (tc *Context, end func(status int))
+}
+
+// startSpan starts a span for the request using the server's Tracer, if configured, returning the Context that should be used by the handler.
+// The span is ended by endSpan.
+func (s *Server) startSpan(c *Context, route string) (tc *Context) {
+	if s.Tracer == nil {
+		return c
+	}
+	name := route
+	if name == "" {
+		name = c.Request.Method()
+	}
+	tc, c.spanEnd = s.Tracer.StartSpan(c, name)
+	return
+}
+
+// endSpan ends the request's span, if one was started, with the final status code.
+// If no status code was written, such as when a panic went unrecovered, a 500 is reported.
+func (c *Context) endSpan() {
+	end := c.spanEnd
+	if end == nil {
+		return
+	}
+	c.spanEnd = nil
+	status := c.Response.GetStatusCode()
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	end(status)
+}